@@ -5,10 +5,19 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"realtime-market-engine/internal/alert"
 	"realtime-market-engine/internal/backtest"
 	"realtime-market-engine/internal/binance"
+	"realtime-market-engine/internal/binancedata"
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/klinecache"
+	"realtime-market-engine/internal/market"
+	"realtime-market-engine/internal/trend"
 )
 
 func main() {
@@ -16,14 +25,22 @@ func main() {
 	var interval string
 	var start string
 	var end string
+	var restURL string
+	var cacheDir string
+	var offline bool
+	var data string
+	var bars string
 
 	var initialEquity float64
 	var fee float64
 	var slippage float64
 	var allowShort bool
 
+	var emaMode string
 	var emaFast int
 	var emaSlow int
+	var emaFastHalfLife time.Duration
+	var emaSlowHalfLife time.Duration
 	var trendConfirm int
 	var trendMinDiff float64
 	var trendCooldown time.Duration
@@ -31,6 +48,8 @@ func main() {
 	var breakoutLookback time.Duration
 	var breakoutPct float64
 	var breakoutCooldown time.Duration
+	var breakoutMode string
+	var breakoutK float64
 
 	var stopLoss float64
 	var takeProfit float64
@@ -39,14 +58,22 @@ func main() {
 	flag.StringVar(&interval, "interval", "1m", "Binance kline interval (e.g. 1m,5m)")
 	flag.StringVar(&start, "start", "", "Start time RFC3339 (e.g. 2026-01-01T00:00:00Z)")
 	flag.StringVar(&end, "end", "", "End time RFC3339 (e.g. 2026-01-02T00:00:00Z)")
+	flag.StringVar(&restURL, "rest-url", "https://api.binance.com", "Binance REST base URL")
+	flag.StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "Directory for cached klines (empty disables the cache)")
+	flag.BoolVar(&offline, "offline", false, "Serve klines from -cache-dir only, never hit the network")
+	flag.StringVar(&data, "data", "", "Read klines or aggTrades from data.binance.vision zip/CSV files (glob, e.g. 'data/BTCUSDT-1m-*.zip') instead of the REST API")
+	flag.StringVar(&bars, "bars", "time", "Bar type: time (of -interval), tick:N, volume:V, dollar:D or renko:BOX; prefix ha: for Heikin-Ashi. Bars other than time need aggTrades -data")
 
 	flag.Float64Var(&initialEquity, "equity", 1000, "Initial equity in quote currency")
 	flag.Float64Var(&fee, "fee", 0.001, "Fee rate per side (0.001 = 0.1%)")
 	flag.Float64Var(&slippage, "slippage", 0.0002, "Slippage rate per fill (0.0002 = 2 bps)")
 	flag.BoolVar(&allowShort, "short", false, "Allow short trades")
 
+	flag.StringVar(&emaMode, "ema-mode", "tick", "Trend EMA mode: tick (windows in candles) or time (half-lives)")
 	flag.IntVar(&emaFast, "ema-fast", 20, "Fast EMA window (candles)")
 	flag.IntVar(&emaSlow, "ema-slow", 50, "Slow EMA window (candles)")
+	flag.DurationVar(&emaFastHalfLife, "ema-fast-half-life", 30*time.Minute, "Fast EMA half-life with -ema-mode=time")
+	flag.DurationVar(&emaSlowHalfLife, "ema-slow-half-life", 2*time.Hour, "Slow EMA half-life with -ema-mode=time")
 	flag.IntVar(&trendConfirm, "trend-confirm", 3, "Confirm trend flip after N consecutive candles")
 	flag.Float64Var(&trendMinDiff, "trend-min-diff", 0.0, "Minimum relative EMA separation (abs(fast-slow)/price) to confirm flip")
 	flag.DurationVar(&trendCooldown, "trend-cooldown", 0, "Minimum time between trend flip notifications")
@@ -54,50 +81,92 @@ func main() {
 	flag.DurationVar(&breakoutLookback, "breakout-lookback", 5*time.Minute, "Breakout lookback window")
 	flag.Float64Var(&breakoutPct, "breakout-pct", 0.001, "Breakout threshold fraction")
 	flag.DurationVar(&breakoutCooldown, "breakout-cooldown", 0, "Minimum time between breakout signals")
+	flag.StringVar(&breakoutMode, "breakout-mode", "pct", "Breakout threshold: pct (-breakout-pct), atr or stddev (-breakout-k times the window's ATR or return standard deviation)")
+	flag.Float64Var(&breakoutK, "breakout-k", 2, "Volatility multiple with -breakout-mode=atr or stddev")
 
 	flag.Float64Var(&stopLoss, "sl", 0.003, "Stop loss percent (0.003 = 0.3%)")
 	flag.Float64Var(&takeProfit, "tp", 0.006, "Take profit percent (0.006 = 0.6%)")
 
 	flag.Parse()
 
-	if start == "" || end == "" {
-		log.Fatalf("-start and -end are required")
-	}
-
-	st, err := time.Parse(time.RFC3339, start)
+	mode, err := trend.ParseEMAMode(emaMode)
 	if err != nil {
-		log.Fatalf("invalid -start: %v", err)
+		log.Fatalf("invalid -ema-mode: %v", err)
 	}
-	et, err := time.Parse(time.RFC3339, end)
+	boMode, err := alert.ParseThresholdMode(breakoutMode)
 	if err != nil {
-		log.Fatalf("invalid -end: %v", err)
+		log.Fatalf("invalid -breakout-mode: %v", err)
 	}
 
-	ctx := context.Background()
-	fetcher := binance.NewKlineFetcher()
-	candles, err := fetcher.FetchKlines(ctx, symbol, interval, st, et)
-	if err != nil {
-		log.Fatalf("fetch klines: %v", err)
+	var st, et time.Time
+	if start != "" {
+		if st, err = time.Parse(time.RFC3339, start); err != nil {
+			log.Fatalf("invalid -start: %v", err)
+		}
+	}
+	if end != "" {
+		if et, err = time.Parse(time.RFC3339, end); err != nil {
+			log.Fatalf("invalid -end: %v", err)
+		}
+	}
+
+	heikinAshi := strings.HasPrefix(bars, "ha:")
+	timeBars := strings.TrimPrefix(bars, "ha:") == "time"
+
+	var candles []candle.Candle
+	if data != "" {
+		// An unquoted glob is expanded by the shell into extra arguments.
+		candles, err = loadData(append([]string{data}, flag.Args()...), symbol, interval, bars, st, et)
+		if err != nil {
+			log.Fatalf("load data: %v", err)
+		}
+	} else {
+		if start == "" || end == "" {
+			log.Fatalf("-start and -end are required")
+		}
+		if !timeBars {
+			log.Fatalf("-bars %s requires aggTrades -data", bars)
+		}
+
+		ctx := context.Background()
+		var fetcher klinecache.Fetcher = binance.NewKlineFetcher(binance.WithRESTBaseURL(restURL))
+		if cacheDir != "" {
+			fetcher = klinecache.New(cacheDir, fetcher, offline)
+		} else if offline {
+			log.Fatalf("-offline requires -cache-dir")
+		}
+		candles, err = fetcher.FetchKlines(ctx, symbol, interval, st, et)
+		if err != nil {
+			log.Fatalf("fetch klines: %v", err)
+		}
+		if heikinAshi {
+			candles = candle.HeikinAshiCandles(candles)
+		}
 	}
 	if len(candles) == 0 {
 		log.Fatalf("no candles fetched")
 	}
 
 	res, err := backtest.Run(candles, backtest.Config{
-		InitialEquity: initialEquity,
-		FeeRate:       fee,
-		SlippageRate:  slippage,
-		AllowShort:    allowShort,
-		StopLossPct:   stopLoss,
-		TakeProfitPct: takeProfit,
-		EmaFast:       emaFast,
-		EmaSlow:       emaSlow,
-		TrendConfirm:  trendConfirm,
-		TrendMinDiff:  trendMinDiff,
-		TrendCooldown: trendCooldown,
+		InitialEquity:    initialEquity,
+		FeeRate:          fee,
+		SlippageRate:     slippage,
+		AllowShort:       allowShort,
+		StopLossPct:      stopLoss,
+		TakeProfitPct:    takeProfit,
+		EmaMode:          mode,
+		EmaFast:          emaFast,
+		EmaSlow:          emaSlow,
+		EmaFastHalfLife:  emaFastHalfLife,
+		EmaSlowHalfLife:  emaSlowHalfLife,
+		TrendConfirm:     trendConfirm,
+		TrendMinDiff:     trendMinDiff,
+		TrendCooldown:    trendCooldown,
 		BreakoutLookback: breakoutLookback,
 		BreakoutPct:      breakoutPct,
 		BreakoutCooldown: breakoutCooldown,
+		BreakoutMode:     boMode,
+		BreakoutK:        breakoutK,
 	})
 	if err != nil {
 		log.Fatalf("backtest: %v", err)
@@ -111,3 +180,70 @@ func main() {
 	fmt.Printf("Win rate: %.2f%%\n", res.WinRate*100)
 	fmt.Printf("Profit factor: %.3f\n", res.ProfitFactor)
 }
+
+// loadData reads the dump files of symbol matching patterns. Kline files must
+// be of interval and only support time bars; aggTrades are aggregated into
+// bars as given by the -bars spec.
+func loadData(patterns []string, symbol, interval, bars string, start, end time.Time) ([]candle.Candle, error) {
+	all, err := binancedata.Glob(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var files []binancedata.File
+	for _, f := range all {
+		if f.Symbol == strings.ToUpper(symbol) {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files for %s among %d matched", symbol, len(all))
+	}
+
+	kind := files[0].Kind
+	for _, f := range files {
+		if f.Kind != kind {
+			return nil, fmt.Errorf("mixed file kinds %s and %s", kind, f.Kind)
+		}
+	}
+
+	if kind != "aggTrades" {
+		if kind != interval {
+			return nil, fmt.Errorf("files hold %s klines but -interval is %s", kind, interval)
+		}
+		heikinAshi := strings.HasPrefix(bars, "ha:")
+		if strings.TrimPrefix(bars, "ha:") != "time" {
+			return nil, fmt.Errorf("-bars %s requires aggTrades files", bars)
+		}
+		candles, err := binancedata.LoadKlines(files, start, end)
+		if err != nil || !heikinAshi {
+			return candles, err
+		}
+		return candle.HeikinAshiCandles(candles), nil
+	}
+
+	d, err := market.ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	b, err := candle.ParseBars(bars, d)
+	if err != nil {
+		return nil, err
+	}
+	if !end.IsZero() {
+		end = end.Add(d)
+	}
+	r, err := binancedata.OpenAggTrades(files, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return binancedata.BarsFromTrades(r, b)
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "realtime-market-engine", "klines")
+}
@@ -3,88 +3,284 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"realtime-market-engine/internal/alert"
 	"realtime-market-engine/internal/binance"
 	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/coinbase"
+	"realtime-market-engine/internal/engine"
 	"realtime-market-engine/internal/httpapi"
+	"realtime-market-engine/internal/kraken"
+	"realtime-market-engine/internal/market"
+	"realtime-market-engine/internal/orderbook"
+	"realtime-market-engine/internal/recorder"
 	"realtime-market-engine/internal/store"
-	"realtime-market-engine/internal/trend"
+	"realtime-market-engine/internal/types"
 )
 
 func main() {
 	var httpAddr string
-	var emaFast int
-	var emaSlow int
-	var confirmTicks int
-	var trendMinDiff float64
-	var trendCooldown time.Duration
-	var candleInterval time.Duration
-	var breakoutLookback time.Duration
-	var breakoutPct float64
-	var breakoutCooldown time.Duration
+	var symbolsFlag string
+	var venuesFlag string
+	var restURL string
+	var wsURL string
+	var klineInterval string
+	var depthBooks bool
+	var bookTicker bool
+	var historyTicks int
+	var historyCandles int
+	var historyMaxAge time.Duration
+	var recordDir string
+	var snapshotFile string
+	var snapshotInterval time.Duration
+	var snapshotMaxAge time.Duration
+	var warmup time.Duration
+	var cfg engine.Config
 	flag.StringVar(&httpAddr, "http", ":8080", "HTTP listen address")
-	flag.IntVar(&emaFast, "ema-fast", 20, "Fast EMA window (ticks)")
-	flag.IntVar(&emaSlow, "ema-slow", 50, "Slow EMA window (ticks)")
-	flag.IntVar(&confirmTicks, "trend-confirm", 3, "Confirm trend flip after N consecutive ticks")
-	flag.Float64Var(&trendMinDiff, "trend-min-diff", 0.00005, "Minimum relative EMA separation (abs(fast-slow)/price) required to confirm a trend flip")
-	flag.DurationVar(&trendCooldown, "trend-cooldown", 10*time.Second, "Minimum time between trend flip notifications")
-	flag.DurationVar(&candleInterval, "candle-interval", 5*time.Second, "Candle aggregation interval")
-	flag.DurationVar(&breakoutLookback, "breakout-lookback", 5*time.Minute, "Breakout lookback window (uses completed candles)")
-	flag.Float64Var(&breakoutPct, "breakout-pct", 0.001, "Breakout threshold as a fraction (0.001 = 0.1%)")
-	flag.DurationVar(&breakoutCooldown, "breakout-cooldown", 30*time.Second, "Minimum time between breakout notifications")
+	flag.StringVar(&restURL, "rest-url", "https://api.binance.com", "Binance REST base URL")
+	flag.StringVar(&wsURL, "ws-url", "wss://stream.binance.com:9443", "Binance WebSocket base URL")
+	flag.StringVar(&symbolsFlag, "symbols", "BTCUSDT", "Comma separated list of Binance symbols (e.g. BTCUSDT,ETHUSDT)")
+	flag.StringVar(&venuesFlag, "venues", "binance", "Comma separated list of trade venues (binance, coinbase, kraken)")
+	flag.StringVar(&klineInterval, "kline-interval", "", "Use closed Binance klines of this interval (e.g. 1m) for breakouts instead of aggregating trades")
+	flag.BoolVar(&bookTicker, "book-ticker", true, "Track best bid/ask from the bookTicker stream")
+	flag.BoolVar(&depthBooks, "depth", false, "Maintain local order books from the diff depth stream (served on /orderbook/{symbol})")
+	flag.IntVar(&historyTicks, "history-ticks", 100000, "Ticks kept per symbol for /history (0 disables)")
+	flag.IntVar(&historyCandles, "history-candles", 10000, "Completed candles kept per symbol for /history (0 disables)")
+	flag.DurationVar(&historyMaxAge, "history-max-age", 24*time.Hour, "Maximum age of history entries (0 keeps until evicted by count)")
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&recordDir, "record-dir", "", "Record every trade into compressed segment files in this directory")
+	flag.StringVar(&snapshotFile, "snapshot-file", "", "Persist detector and aggregator state to this file and restore it on start")
+	flag.DurationVar(&snapshotInterval, "snapshot-interval", time.Minute, "How often to save the snapshot (in addition to shutdown)")
+	flag.DurationVar(&snapshotMaxAge, "snapshot-max-age", 15*time.Minute, "Discard snapshots older than this on start (0 accepts any age)")
+	flag.DurationVar(&warmup, "warmup", 0, "Seed the Binance pipelines from this much kline history on start (e.g. 2h); /ready reports 503 until done, or degraded if it fails")
 	flag.Parse()
 
+	symbols := parseSymbols(symbolsFlag)
+	if len(symbols) == 0 {
+		log.Fatalf("-symbols must list at least one symbol")
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	st := store.NewPriceStore()
+	st := store.NewPriceStore(store.WithRetention(store.Retention{
+		Ticks:   historyTicks,
+		Candles: historyCandles,
+		MaxAge:  historyMaxAge,
+	}))
 	hub := httpapi.NewHub()
 	go hub.Run(ctx)
 
-	detector := trend.NewEMACrossoverDetector(emaFast, emaSlow, confirmTicks, trendMinDiff, trendCooldown)
-	agg := candle.NewAggregator(candleInterval)
-	breakout := alert.NewBreakoutDetector(breakoutLookback, breakoutPct, breakoutCooldown)
+	endpoints := []binance.Option{binance.WithRESTBaseURL(restURL), binance.WithWSBaseURL(wsURL)}
+	backfills := binance.WithBackfillHandler(func(bf binance.BackfillEvent) {
+		b, err := json.Marshal(bf)
+		if err == nil {
+			hub.PublishJSON(b)
+		}
+		log.Printf("backfill: %s ids %d-%d trades=%d", bf.Symbol, bf.FromID, bf.ToID, bf.Trades)
+	})
+
+	var sources []market.MarketSource
+	hasBinance := false
+	for _, v := range strings.Split(venuesFlag, ",") {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "binance":
+			sources = append(sources, binance.NewSource(append(endpoints, backfills)...))
+			hasBinance = true
+		case "coinbase":
+			sources = append(sources, coinbase.NewSource())
+		case "kraken":
+			sources = append(sources, kraken.NewSource())
+		case "":
+		default:
+			log.Fatalf("unknown venue %q", v)
+		}
+	}
+	if len(sources) == 0 {
+		log.Fatalf("-venues must list at least one venue")
+	}
+
+	cfg.Klines = klineInterval != ""
+	eng := engine.New(cfg, st, hub)
+	restored := false
+	if snapshotFile != "" {
+		snap, err := engine.LoadSnapshot(snapshotFile, snapshotMaxAge)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			log.Printf("snapshot not restored: %v", err)
+		default:
+			if err := eng.Restore(snap); err != nil {
+				log.Printf("snapshot partially restored: %v", err)
+			}
+			restored = true
+			log.Printf("restored %d pipelines from snapshot saved at %s", len(snap.Pipelines), snap.SavedAt.Format(time.RFC3339))
+		}
+	}
+	saveSnapshot := func() {
+		if err := engine.SaveSnapshot(snapshotFile, eng.Snapshot()); err != nil {
+			log.Printf("snapshot save error: %v", err)
+		}
+	}
 
-	events, err := binance.StartAggTradeListener(ctx, "BTCUSDT")
-	if err != nil {
-		log.Fatalf("binance listener error: %v", err)
+	var rec *recorder.Recorder
+	if recordDir != "" {
+		var err error
+		rec, err = recorder.New(recordDir)
+		if err != nil {
+			log.Fatalf("recorder error: %v", err)
+		}
+		log.Printf("recording trades to %s", recordDir)
 	}
 
+	events := make(chan types.PriceEvent, 1024)
+	klines := make(chan candle.Candle, 100)
+	for _, src := range sources {
+		ch, err := src.Trades(ctx, symbols)
+		if err != nil {
+			log.Fatalf("%s listener error: %v", src.Name(), err)
+		}
+		go func() {
+			for ev := range ch {
+				events <- ev
+			}
+		}()
+
+		if klineInterval == "" {
+			continue
+		}
+		for _, sym := range symbols {
+			ch, err := src.Candles(ctx, sym, klineInterval)
+			if err != nil {
+				log.Fatalf("%s kline listener error: %v", src.Name(), err)
+			}
+			go func() {
+				for c := range ch {
+					klines <- c
+				}
+			}()
+		}
+	}
+
+	// The warm-up klines are fetched in the background while the live streams
+	// run; the dispatch goroutine applies them and holds back each symbol's
+	// live events until then, so the engine is only touched from one goroutine.
+	var status atomic.Value
+	status.Store(httpapi.StatusReady)
+	var warmed <-chan warmResult
+	var gate *warmupGate
+	if warmup > 0 && hasBinance && !restored {
+		status.Store(httpapi.StatusWarmingUp)
+		warmed = fetchWarmup(ctx, binance.NewKlineFetcher(endpoints...), symbols, klineInterval, warmup)
+		gate = newWarmupGate(symbols)
+	}
+	if warmup > 0 && restored {
+		log.Printf("warm-up skipped: state restored from snapshot")
+	}
+
+	dispatchDone := make(chan struct{})
 	go func() {
-		for ev := range events {
-			st.Update(ev)
-			hub.PublishPrice(ev)
-
-			if c, ok := agg.Push(ev); ok {
-				if bo, ok := breakout.Push(c); ok {
-					b, err := json.Marshal(bo)
-					if err == nil {
-						hub.PublishJSON(b)
+		defer close(dispatchDone)
+		flush := time.NewTicker(time.Second)
+		defer flush.Stop()
+		candleClock := time.NewTicker(100 * time.Millisecond)
+		defer candleClock.Stop()
+		var snapshots <-chan time.Time
+		if snapshotFile != "" && snapshotInterval > 0 {
+			t := time.NewTicker(snapshotInterval)
+			defer t.Stop()
+			snapshots = t.C
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				// With snapshots the open candles survive the restart instead.
+				if snapshotFile != "" {
+					saveSnapshot()
+				} else {
+					eng.Flush()
+				}
+				if rec != nil {
+					if err := rec.Close(); err != nil {
+						log.Printf("recorder close error: %v", err)
 					}
-					log.Printf("breakout: %s %s", bo.Symbol, bo.Dir)
 				}
-			}
-
-			if change, ok := detector.Push(ev); ok {
-				b, err := json.Marshal(change)
-				if err == nil {
-					hub.PublishJSON(b)
+				return
+			case now := <-candleClock.C:
+				eng.Advance(now)
+			case <-snapshots:
+				saveSnapshot()
+			case <-flush.C:
+				if rec != nil {
+					if err := rec.Flush(); err != nil {
+						log.Printf("recorder flush error: %v", err)
+					}
+				}
+			case res, ok := <-warmed:
+				if !ok {
+					warmed = nil
+					if gate.failed > 0 {
+						log.Printf("warm-up failed for %d of %d symbols", gate.failed, len(symbols))
+						status.Store(httpapi.StatusDegraded)
+					} else {
+						status.Store(httpapi.StatusReady)
+					}
+					gate = nil
+					continue
 				}
-				log.Printf("trend change: %s", change.Trend)
+				gate.apply(eng, res)
+			case ev := <-events:
+				if rec != nil {
+					if err := rec.Write(ev); err != nil {
+						log.Printf("recorder write error: %v", err)
+					}
+				}
+				if gate != nil && gate.holdTrade(ev) {
+					continue
+				}
+				eng.HandleTrade(ev)
+			case c := <-klines:
+				if gate != nil && gate.holdKline(c) {
+					continue
+				}
+				eng.HandleKline(c)
 			}
 		}
 	}()
 
+	if bookTicker {
+		quotes, err := binance.StartBookTickerStreams(ctx, symbols, endpoints...)
+		if err != nil {
+			log.Fatalf("binance bookTicker error: %v", err)
+		}
+		go func() {
+			for q := range quotes {
+				st.UpdateQuote(q)
+				hub.PublishQuote(q)
+			}
+		}()
+	}
+
 	mux := http.NewServeMux()
 	routes := httpapi.NewRoutes(st, hub)
+	routes.SetReadiness(func() string { return status.Load().(string) })
+	routes.SetMetrics(func() any { return eng.Metrics() })
+	if depthBooks {
+		books := orderbook.NewBooks()
+		if err := binance.StartDepthStreams(ctx, books, symbols, endpoints...); err != nil {
+			log.Fatalf("binance depth error: %v", err)
+		}
+		routes.SetOrderBooks(books)
+	}
 	routes.Register(mux)
 
 	srv := &http.Server{
@@ -100,8 +296,9 @@ func main() {
 		_ = srv.Shutdown(shutdownCtx)
 	}()
 
-	log.Printf("engine listening on %s", httpAddr)
+	log.Printf("engine listening on %s (symbols: %s, venues: %s)", httpAddr, strings.Join(symbols, ","), venuesFlag)
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("http server error: %v", err)
 	}
+	<-dispatchDone
 }
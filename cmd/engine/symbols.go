@@ -0,0 +1,22 @@
+package main
+
+import "strings"
+
+// parseSymbols splits a comma separated symbol list, upper-cases it and drops
+// blanks and duplicates while keeping the original order.
+func parseSymbols(s string) []string {
+	var out []string
+	seen := make(map[string]struct{})
+	for _, part := range strings.Split(s, ",") {
+		sym := strings.ToUpper(strings.TrimSpace(part))
+		if sym == "" {
+			continue
+		}
+		if _, ok := seen[sym]; ok {
+			continue
+		}
+		seen[sym] = struct{}{}
+		out = append(out, sym)
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"realtime-market-engine/internal/binance"
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/engine"
+	"realtime-market-engine/internal/types"
+)
+
+// warmResult is the kline history of one symbol fetched for the warm-up.
+type warmResult struct {
+	symbol string
+	klines []candle.Candle
+	err    error
+}
+
+// fetchWarmup fetches the last d of klines of every symbol in the background
+// and sends them on the returned channel, which is closed when done. The REST
+// calls stay off the dispatch goroutine so the live streams keep draining.
+// Without -kline-interval, 1s klines stand in for trades.
+func fetchWarmup(ctx context.Context, fetcher *binance.KlineFetcher, symbols []string, interval string, d time.Duration) <-chan warmResult {
+	if interval == "" {
+		interval = "1s"
+	}
+	out := make(chan warmResult)
+	go func() {
+		defer close(out)
+		end := time.Now()
+		for _, sym := range symbols {
+			klines, err := fetcher.FetchKlines(ctx, sym, interval, end.Add(-d), end)
+			if err == nil {
+				log.Printf("warm-up %s: %d %s klines over %s", sym, len(klines), interval, d)
+			}
+			select {
+			case out <- warmResult{symbol: sym, klines: klines, err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// warmupGate holds the live Binance trades and klines of each symbol until
+// its warm-up is applied, so that the detectors see the history first and
+// the dispatch goroutine never blocks the stream readers.
+type warmupGate struct {
+	trades map[string][]types.PriceEvent
+	klines map[string][]candle.Candle
+	failed int
+}
+
+func newWarmupGate(symbols []string) *warmupGate {
+	g := &warmupGate{
+		trades: make(map[string][]types.PriceEvent),
+		klines: make(map[string][]candle.Candle),
+	}
+	for _, sym := range symbols {
+		g.trades[sym] = nil
+	}
+	return g
+}
+
+// holdTrade buffers ev if its symbol is still warming up.
+func (g *warmupGate) holdTrade(ev types.PriceEvent) bool {
+	buf, ok := g.trades[ev.Symbol]
+	if !ok || ev.Source != "binance" {
+		return false
+	}
+	g.trades[ev.Symbol] = append(buf, ev)
+	return true
+}
+
+// holdKline buffers c if its symbol is still warming up.
+func (g *warmupGate) holdKline(c candle.Candle) bool {
+	if _, ok := g.trades[c.Symbol]; !ok || c.Source != "binance" {
+		return false
+	}
+	g.klines[c.Symbol] = append(g.klines[c.Symbol], c)
+	return true
+}
+
+// apply seeds eng with res and releases the events held for its symbol; those
+// covered by the warm-up are dropped by the engine. A failed warm-up still
+// releases them so the symbol runs cold.
+func (g *warmupGate) apply(eng *engine.Engine, res warmResult) {
+	if res.err != nil {
+		log.Printf("warm-up %s: %v", res.symbol, res.err)
+		g.failed++
+	} else {
+		eng.Warmup("binance", res.symbol, res.klines)
+	}
+	for _, c := range g.klines[res.symbol] {
+		eng.HandleKline(c)
+	}
+	for _, ev := range g.trades[res.symbol] {
+		eng.HandleTrade(ev)
+	}
+	delete(g.klines, res.symbol)
+	delete(g.trades, res.symbol)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+// csvTicks reads trades from a CSV file with a header row. Recognised columns
+// are time (or timestamp), symbol, price, qty (or quantity), and optionally
+// source and buyer_is_maker. Times are RFC3339 or unix milliseconds. Rows
+// outside [from, to) are skipped when the bounds are set.
+type csvTicks struct {
+	f        *os.File
+	r        *csv.Reader
+	col      map[string]int
+	from, to time.Time
+	line     int
+}
+
+func openCSV(path string, from, to time.Time) (*csvTicks, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: read header: %w", path, err)
+	}
+	col := make(map[string]int)
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	alias(col, "time", "timestamp")
+	alias(col, "qty", "quantity")
+	alias(col, "buyer_is_maker", "is_buyer_maker")
+	for _, name := range []string{"time", "symbol", "price", "qty"} {
+		if _, ok := col[name]; !ok {
+			_ = f.Close()
+			return nil, fmt.Errorf("%s: missing %q column", path, name)
+		}
+	}
+
+	return &csvTicks{f: f, r: r, col: col, from: from, to: to, line: 1}, nil
+}
+
+func alias(col map[string]int, name, other string) {
+	if _, ok := col[name]; ok {
+		return
+	}
+	if i, ok := col[other]; ok {
+		col[name] = i
+	}
+}
+
+func (c *csvTicks) Next() (types.PriceEvent, error) {
+	for {
+		rec, err := c.r.Read()
+		if err != nil {
+			return types.PriceEvent{}, err
+		}
+		c.line++
+
+		ev, err := c.parse(rec)
+		if err != nil {
+			return types.PriceEvent{}, fmt.Errorf("line %d: %w", c.line, err)
+		}
+		if !c.from.IsZero() && ev.Timestamp.Before(c.from) {
+			continue
+		}
+		if !c.to.IsZero() && !ev.Timestamp.Before(c.to) {
+			return types.PriceEvent{}, io.EOF
+		}
+		return ev, nil
+	}
+}
+
+func (c *csvTicks) parse(rec []string) (types.PriceEvent, error) {
+	field := func(name string) string {
+		i, ok := c.col[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	ts, err := parseTime(field("time"))
+	if err != nil {
+		return types.PriceEvent{}, err
+	}
+	price, err := strconv.ParseFloat(field("price"), 64)
+	if err != nil {
+		return types.PriceEvent{}, fmt.Errorf("price: %w", err)
+	}
+	qty, err := strconv.ParseFloat(field("qty"), 64)
+	if err != nil {
+		return types.PriceEvent{}, fmt.Errorf("qty: %w", err)
+	}
+
+	ev := types.PriceEvent{
+		Symbol:    strings.ToUpper(field("symbol")),
+		Price:     price,
+		Quantity:  qty,
+		Timestamp: ts,
+		Source:    field("source"),
+	}
+	if ev.Source == "" {
+		ev.Source = "binance"
+	}
+	if s := field("buyer_is_maker"); s != "" {
+		ev.BuyerIsMaker, err = strconv.ParseBool(s)
+		if err != nil {
+			return types.PriceEvent{}, fmt.Errorf("buyer_is_maker: %w", err)
+		}
+	}
+	return ev, nil
+}
+
+func (c *csvTicks) Close() error {
+	return c.f.Close()
+}
+
+func parseTime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time %q: want RFC3339 or unix ms", s)
+	}
+	return t, nil
+}
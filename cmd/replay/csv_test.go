@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trades.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCSVTicks(t *testing.T) {
+	path := writeCSV(t, `Timestamp,symbol,price,quantity,source,is_buyer_maker
+2026-02-08T10:00:00Z,btcusdt,100,0.5,,false
+1770544801000,BTCUSDT,101,0.25,kraken,true
+2026-02-08T10:00:02.5Z,BTCUSDT,102,1,,
+2026-02-08T10:00:03Z,BTCUSDT,103,1,,
+`)
+	from := time.Date(2026, 2, 8, 10, 0, 1, 0, time.UTC)
+	to := time.Date(2026, 2, 8, 10, 0, 3, 0, time.UTC)
+	c, err := openCSV(path, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ev, err := c.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The 10:00:00 row is before from; unix milliseconds and aliases are read.
+	if !ev.Timestamp.Equal(from) || ev.Symbol != "BTCUSDT" || ev.Price != 101 || ev.Quantity != 0.25 || ev.Source != "kraken" || !ev.BuyerIsMaker {
+		t.Fatalf("first tick = %+v", ev)
+	}
+	ev, err = c.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Price != 102 || ev.Source != "binance" || ev.BuyerIsMaker {
+		t.Fatalf("second tick = %+v", ev)
+	}
+	// The 10:00:03 row is at to, which is exclusive.
+	if _, err := c.Next(); err != io.EOF {
+		t.Fatalf("Next = %v, want io.EOF", err)
+	}
+}
+
+func TestCSVErrors(t *testing.T) {
+	if _, err := openCSV(writeCSV(t, "time,symbol,price\n"), time.Time{}, time.Time{}); err == nil {
+		t.Error("header without qty accepted")
+	}
+
+	c, err := openCSV(writeCSV(t, "time,symbol,price,qty\n2026-02-08T10:00:00Z,BTCUSDT,abc,1\n"), time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Next(); err == nil || err == io.EOF {
+		t.Errorf("Next = %v, want a parse error", err)
+	}
+}
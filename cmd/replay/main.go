@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"realtime-market-engine/internal/engine"
+	"realtime-market-engine/internal/httpapi"
+	"realtime-market-engine/internal/recorder"
+	"realtime-market-engine/internal/store"
+)
+
+func main() {
+	var httpAddr string
+	var dir string
+	var csvPath string
+	var fromStr string
+	var toStr string
+	var speedStr string
+	var paused bool
+	var historyTicks int
+	var historyCandles int
+	var historyMaxAge time.Duration
+	var cfg engine.Config
+	flag.StringVar(&httpAddr, "http", ":8080", "HTTP listen address")
+	flag.StringVar(&dir, "dir", "", "Directory of recorded segments (engine -record-dir)")
+	flag.StringVar(&csvPath, "csv", "", "CSV file of trades (time,symbol,price,qty[,source,buyer_is_maker])")
+	flag.StringVar(&fromStr, "from", "", "Replay trades at or after this time (RFC3339)")
+	flag.StringVar(&toStr, "to", "", "Replay trades before this time (RFC3339)")
+	flag.StringVar(&speedStr, "speed", "1x", "Replay speed: a multiplier such as 1x or 10x, or max")
+	flag.BoolVar(&paused, "paused", false, "Start paused; resume with POST /replay/resume")
+	flag.IntVar(&historyTicks, "history-ticks", 100000, "Ticks kept per symbol for /history (0 disables)")
+	flag.IntVar(&historyCandles, "history-candles", 10000, "Completed candles kept per symbol for /history (0 disables)")
+	flag.DurationVar(&historyMaxAge, "history-max-age", 24*time.Hour, "Maximum age of history entries (0 keeps until evicted by count)")
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if (dir == "") == (csvPath == "") {
+		log.Fatalf("exactly one of -dir or -csv is required")
+	}
+	speed, err := parseSpeed(speedStr)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	var from, to time.Time
+	if fromStr != "" {
+		if from, err = time.Parse(time.RFC3339, fromStr); err != nil {
+			log.Fatalf("invalid -from: %v", err)
+		}
+	}
+	if toStr != "" {
+		if to, err = time.Parse(time.RFC3339, toStr); err != nil {
+			log.Fatalf("invalid -to: %v", err)
+		}
+	}
+
+	open := func() (tickSource, error) {
+		if dir != "" {
+			return recorder.OpenDir(dir, from, to)
+		}
+		return openCSV(csvPath, from, to)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	st := store.NewPriceStore(store.WithRetention(store.Retention{
+		Ticks:   historyTicks,
+		Candles: historyCandles,
+		MaxAge:  historyMaxAge,
+	}))
+	hub := httpapi.NewHub()
+	go hub.Run(ctx)
+
+	eng := engine.New(cfg, st, hub)
+	p := newPlayer(open, eng, st, hub, speed, paused)
+	go func() {
+		if err := p.Run(ctx); err != nil {
+			log.Printf("replay error: %v", err)
+			stop()
+		}
+	}()
+
+	mux := http.NewServeMux()
+	routes := httpapi.NewRoutes(st, hub)
+	routes.SetMetrics(func() any { return eng.Metrics() })
+	routes.Register(mux)
+	p.Register(mux)
+
+	srv := &http.Server{
+		Addr:              httpAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("replay listening on %s (speed %s)", httpAddr, formatSpeed(speed))
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("http server error: %v", err)
+	}
+}
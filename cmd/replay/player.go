@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-market-engine/internal/engine"
+	"realtime-market-engine/internal/httpapi"
+	"realtime-market-engine/internal/store"
+	"realtime-market-engine/internal/types"
+)
+
+// advanceEvery is how often, in wall time, the engine clock is advanced while
+// waiting for the next tick; the live engine uses the same period.
+const advanceEvery = 100 * time.Millisecond
+
+type tickSource interface {
+	Next() (types.PriceEvent, error)
+	Close() error
+}
+
+// player feeds recorded ticks into the engine at a configurable speed and
+// advances the engine clock in simulated time, so candles complete as they
+// would live. The engine is only touched from Run; HTTP handlers change the
+// playback state under mu and wake Run up.
+type player struct {
+	open func() (tickSource, error)
+	eng  *engine.Engine
+	st   *store.PriceStore
+	hub  *httpapi.Hub
+
+	mu      sync.Mutex
+	speed   float64 // 0 plays as fast as possible
+	paused  bool
+	seek    *time.Time
+	simTime time.Time
+	events  int64
+	done    bool
+	wake    chan struct{}
+}
+
+func newPlayer(open func() (tickSource, error), eng *engine.Engine, st *store.PriceStore, hub *httpapi.Hub, speed float64, paused bool) *player {
+	return &player{
+		open:   open,
+		eng:    eng,
+		st:     st,
+		hub:    hub,
+		speed:  speed,
+		paused: paused,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// ReplayStatus is published on /ws whenever the playback state changes.
+type ReplayStatus struct {
+	Type   string    `json:"type"`
+	State  string    `json:"state"` // playing, paused or finished
+	Speed  string    `json:"speed"`
+	Time   time.Time `json:"time"`
+	Events int64     `json:"events"`
+}
+
+func (p *player) Run(ctx context.Context) error {
+	src, err := p.open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	var (
+		pending    *types.PriceEvent
+		anchorSim  time.Time
+		anchorWall time.Time
+	)
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		p.mu.Lock()
+		target := p.seek
+		p.seek = nil
+		paused := p.paused
+		speed := p.speed
+		p.mu.Unlock()
+
+		if target != nil {
+			// Seeking restarts from a clean state at the target so that a
+			// replay from a given point is always deterministic.
+			_ = src.Close()
+			if src, err = p.open(); err != nil {
+				return err
+			}
+			p.eng.Reset()
+			p.st.Reset()
+			pending, anchorSim = nil, time.Time{}
+
+			p.mu.Lock()
+			p.done, p.events, p.simTime = false, 0, *target
+			p.mu.Unlock()
+
+			for {
+				ev, err := src.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if !ev.Timestamp.Before(*target) {
+					pending = &ev
+					break
+				}
+			}
+			if pending == nil {
+				p.finish()
+			}
+			p.publishStatus()
+			continue
+		}
+
+		if paused {
+			anchorSim = time.Time{}
+			if !p.sleep(ctx, nil) {
+				return nil
+			}
+			continue
+		}
+
+		if pending == nil {
+			p.mu.Lock()
+			done := p.done
+			p.mu.Unlock()
+			if done {
+				if !p.sleep(ctx, nil) {
+					return nil
+				}
+				continue
+			}
+
+			ev, err := src.Next()
+			if err == io.EOF {
+				p.finish()
+				p.publishStatus()
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			pending = &ev
+		}
+
+		if speed > 0 {
+			if anchorSim.IsZero() {
+				anchorSim, anchorWall = pending.Timestamp, time.Now()
+			}
+			due := anchorWall.Add(time.Duration(float64(pending.Timestamp.Sub(anchorSim)) / speed))
+			if wait := time.Until(due); wait > 0 {
+				// Keep the engine clock running while waiting, as the live
+				// engine does, so quiet periods still complete candles.
+				timer := time.NewTimer(min(wait, advanceEvery))
+				woken := !p.sleep(ctx, timer.C)
+				timer.Stop()
+				if ctx.Err() != nil {
+					return nil
+				}
+				if woken {
+					anchorSim = time.Time{}
+				} else {
+					p.eng.Advance(anchorSim.Add(time.Duration(float64(time.Since(anchorWall)) * speed)))
+				}
+				continue
+			}
+		}
+
+		// The simulated clock reaches the tick before the tick is handled.
+		p.eng.Advance(pending.Timestamp)
+		p.eng.HandleTrade(*pending)
+		p.mu.Lock()
+		p.simTime = pending.Timestamp
+		p.events++
+		p.mu.Unlock()
+		pending = nil
+	}
+}
+
+// sleep blocks until a control change, ctx cancellation or timer fires. It
+// returns false when woken by anything other than the timer.
+func (p *player) sleep(ctx context.Context, timer <-chan time.Time) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-p.wake:
+		return timer == nil
+	case <-timer:
+		return true
+	}
+}
+
+func (p *player) finish() {
+	p.eng.Flush()
+	p.mu.Lock()
+	p.done = true
+	events := p.events
+	p.mu.Unlock()
+	log.Printf("replay finished after %d events", events)
+}
+
+func (p *player) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *player) status() ReplayStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := ReplayStatus{Type: "replay", State: "playing", Speed: formatSpeed(p.speed), Time: p.simTime, Events: p.events}
+	switch {
+	case p.paused:
+		st.State = "paused"
+	case p.done:
+		st.State = "finished"
+	}
+	return st
+}
+
+func (p *player) publishStatus() {
+	b, err := json.Marshal(p.status())
+	if err == nil {
+		p.hub.PublishJSON(b)
+	}
+}
+
+func (p *player) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /replay", p.handleStatus)
+	mux.HandleFunc("POST /replay/pause", p.handlePause)
+	mux.HandleFunc("POST /replay/resume", p.handleResume)
+	mux.HandleFunc("POST /replay/seek", p.handleSeek)
+	mux.HandleFunc("POST /replay/speed", p.handleSpeed)
+}
+
+func (p *player) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.status())
+}
+
+func (p *player) handlePause(w http.ResponseWriter, r *http.Request) {
+	p.update(func() { p.paused = true })
+	writeJSON(w, http.StatusOK, p.status())
+}
+
+func (p *player) handleResume(w http.ResponseWriter, r *http.Request) {
+	p.update(func() { p.paused = false })
+	writeJSON(w, http.StatusOK, p.status())
+}
+
+func (p *player) handleSeek(w http.ResponseWriter, r *http.Request) {
+	t, err := parseTime(r.URL.Query().Get("time"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.update(func() { p.seek = &t })
+	writeJSON(w, http.StatusAccepted, p.status())
+}
+
+func (p *player) handleSpeed(w http.ResponseWriter, r *http.Request) {
+	speed, err := parseSpeed(r.URL.Query().Get("x"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.update(func() { p.speed = speed })
+	writeJSON(w, http.StatusOK, p.status())
+}
+
+func (p *player) update(fn func()) {
+	p.mu.Lock()
+	fn()
+	p.mu.Unlock()
+	p.notify()
+	p.publishStatus()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// parseSpeed accepts "max" or a positive multiplier such as "1", "10x" or "0.5x".
+func parseSpeed(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "max" {
+		return 0, nil
+	}
+	x, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || x <= 0 {
+		return 0, fmt.Errorf("invalid speed %q: want max or a positive multiplier like 10x", s)
+	}
+	return x, nil
+}
+
+func formatSpeed(x float64) string {
+	if x == 0 {
+		return "max"
+	}
+	return strconv.FormatFloat(x, 'f', -1, 64) + "x"
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/engine"
+	"realtime-market-engine/internal/httpapi"
+	"realtime-market-engine/internal/store"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestParseSpeed(t *testing.T) {
+	for in, want := range map[string]float64{"max": 0, " MAX ": 0, "1x": 1, "10X": 10, "0.5x": 0.5, "2": 2} {
+		got, err := parseSpeed(in)
+		if err != nil || got != want {
+			t.Errorf("parseSpeed(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "x", "0x", "-1x", "fast"} {
+		if _, err := parseSpeed(in); err == nil {
+			t.Errorf("parseSpeed(%q) succeeded", in)
+		}
+	}
+}
+
+var t0 = time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+
+// tradesCSV has one BTCUSDT trade at each offset, priced 100 plus its index.
+func tradesCSV(t *testing.T, offsets ...time.Duration) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("time,symbol,price,qty\n")
+	for i, d := range offsets {
+		fmt.Fprintf(&b, "%s,BTCUSDT,%d,1\n", t0.Add(d).Format(time.RFC3339Nano), 100+i)
+	}
+	return writeCSV(t, b.String())
+}
+
+type testReplay struct {
+	p   *player
+	st  *store.PriceStore
+	srv *httptest.Server
+	ws  chan map[string]any
+}
+
+// startReplay runs a player on the CSV at path behind the replay HTTP API
+// and returns the messages of a /ws client connected before playback.
+func startReplay(t *testing.T, path string, speed float64, paused bool) *testReplay {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var cfg engine.Config
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	st := store.NewPriceStore(store.WithRetention(store.Retention{Ticks: 100, Candles: 100}))
+	hub := httpapi.NewHub()
+	go hub.Run(ctx)
+	eng := engine.New(cfg, st, hub)
+	p := newPlayer(func() (tickSource, error) { return openCSV(path, time.Time{}, time.Time{}) }, eng, st, hub, speed, paused)
+
+	mux := http.NewServeMux()
+	httpapi.NewRoutes(st, hub).Register(mux)
+	p.Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	ws := make(chan map[string]any, 1024)
+	go func() {
+		for {
+			var m map[string]any
+			if err := conn.ReadJSON(&m); err != nil {
+				return
+			}
+			ws <- m
+		}
+	}()
+
+	// The hub registers the client asynchronously; publish until it arrives.
+	for synced := false; !synced; {
+		p.publishStatus()
+		select {
+		case <-ws:
+			synced = true
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	go func() {
+		if err := p.Run(ctx); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+	return &testReplay{p: p, st: st, srv: srv, ws: ws}
+}
+
+func (r *testReplay) post(t *testing.T, path string, want int) {
+	t.Helper()
+	resp, err := http.Post(r.srv.URL+path, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != want {
+		t.Fatalf("POST %s: status %d, want %d", path, resp.StatusCode, want)
+	}
+}
+
+func (r *testReplay) status(t *testing.T) ReplayStatus {
+	t.Helper()
+	resp, err := http.Get(r.srv.URL + "/replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st ReplayStatus
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+// waitStatus collects /ws messages until a replay status matching ok.
+func (r *testReplay) waitStatus(t *testing.T, ok func(ReplayStatus) bool) (ReplayStatus, []map[string]any) {
+	t.Helper()
+	var seen []map[string]any
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case m := <-r.ws:
+			seen = append(seen, m)
+			if m["type"] != "replay" {
+				continue
+			}
+			b, _ := json.Marshal(m)
+			var st ReplayStatus
+			_ = json.Unmarshal(b, &st)
+			if ok(st) {
+				return st, seen
+			}
+		case <-timeout:
+			t.Fatalf("no matching replay status; got %v", seen)
+		}
+	}
+}
+
+func TestReplaySeekPauseFinish(t *testing.T) {
+	var offsets []time.Duration
+	for i := 0; i < 20; i++ {
+		offsets = append(offsets, time.Duration(i)*time.Second)
+	}
+	r := startReplay(t, tradesCSV(t, offsets...), 0, true)
+
+	if st := r.status(t); st.State != "paused" || st.Events != 0 || st.Speed != "max" {
+		t.Fatalf("initial status = %+v", st)
+	}
+
+	seekTo := t0.Add(10 * time.Second)
+	r.post(t, "/replay/seek?time="+seekTo.Format(time.RFC3339), http.StatusAccepted)
+	r.waitStatus(t, func(st ReplayStatus) bool { return st.Time.Equal(seekTo) })
+	if st := r.status(t); st.State != "paused" || st.Events != 0 {
+		t.Fatalf("status after seek = %+v", st)
+	}
+	if ticks := r.st.Last("binance", "BTCUSDT", 100); len(ticks) != 0 {
+		t.Fatalf("%d ticks played while paused", len(ticks))
+	}
+
+	r.post(t, "/replay/resume", http.StatusOK)
+	st, seen := r.waitStatus(t, func(st ReplayStatus) bool { return st.State == "finished" })
+	if st.Events != 10 || !st.Time.Equal(t0.Add(19*time.Second)) {
+		t.Fatalf("finished status = %+v", st)
+	}
+
+	// Playback starts at the seek target: trades 10-19 on /ws, in order.
+	var prices []float64
+	for _, m := range seen {
+		if _, ok := m["type"]; !ok {
+			prices = append(prices, m["Price"].(float64))
+		}
+	}
+	if len(prices) != 10 || prices[0] != 110 || prices[9] != 119 {
+		t.Fatalf("prices on /ws = %v", prices)
+	}
+	candles := r.st.LastCandles("binance", "BTCUSDT", 100)
+	if len(candles) != 2 || !candles[0].Start.Equal(seekTo) {
+		t.Fatalf("candles = %+v", candles)
+	}
+	if got := r.status(t).State; got != "finished" {
+		t.Fatalf("state = %s, want finished", got)
+	}
+}
+
+func TestReplayAdvancesClockWhileWaiting(t *testing.T) {
+	// At 100x the 29s gap takes 290ms; the 10:00:00-10:00:05 candle must
+	// complete during it, not when the 10:00:30 trade arrives.
+	r := startReplay(t, tradesCSV(t, 0, time.Second, 30*time.Second), 100, false)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(r.st.LastCandles("binance", "BTCUSDT", 10)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no candle completed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if st := r.p.status(); st.Events != 2 {
+		t.Fatalf("candle completed after %d events, want 2", st.Events)
+	}
+}
@@ -34,7 +34,7 @@ func main() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		event, found := store.Get("BTCUSDT")
+		event, found := store.Get("binance", "BTCUSDT")
 		if found {
 			age := time.Since(event.Timestamp)
 			fmt.Printf("[%s] 🪙 BTC: $%.2f (data %.0f sec ago)\n",
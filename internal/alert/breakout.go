@@ -1,7 +1,8 @@
 package alert
 
 import (
-	"math"
+	"fmt"
+	"sort"
 	"time"
 
 	"realtime-market-engine/internal/candle"
@@ -14,9 +15,31 @@ const (
 	BreakoutDown BreakoutDirection = "down"
 )
 
+// ThresholdMode selects how far beyond the lookback high/low the close must be.
+type ThresholdMode string
+
+const (
+	// ThresholdPct uses a fixed fraction of the level.
+	ThresholdPct ThresholdMode = "pct"
+	// ThresholdATR uses a multiple of the average true range of the window.
+	ThresholdATR ThresholdMode = "atr"
+	// ThresholdStdDev uses a multiple of the standard deviation of the
+	// close-to-close returns of the window, as a fraction of the level.
+	ThresholdStdDev ThresholdMode = "stddev"
+)
+
+func ParseThresholdMode(s string) (ThresholdMode, error) {
+	switch m := ThresholdMode(s); m {
+	case ThresholdPct, ThresholdATR, ThresholdStdDev:
+		return m, nil
+	}
+	return "", fmt.Errorf("alert: invalid breakout threshold mode %q, want pct, atr or stddev", s)
+}
+
 type BreakoutEvent struct {
 	Type      string            `json:"type"`
 	Symbol    string            `json:"symbol"`
+	Source    string            `json:"source,omitempty"`
 	Dir       BreakoutDirection `json:"dir"`
 	Price     float64           `json:"price"`
 	Level     float64           `json:"level"`
@@ -24,19 +47,38 @@ type BreakoutEvent struct {
 	Lookback  string            `json:"lookback"`
 	CandleEnd time.Time         `json:"candleEnd"`
 	Timestamp time.Time         `json:"timestamp"`
+
+	// Mode, Multiplier and Volatility are set for volatility thresholds;
+	// Volatility is the ATR in price units or the return standard deviation.
+	Mode       ThresholdMode `json:"mode,omitempty"`
+	Multiplier float64       `json:"multiplier,omitempty"`
+	Volatility float64       `json:"volatility,omitempty"`
 }
 
 type BreakoutDetector struct {
 	lookback time.Duration
 	pct      float64
 	cooldown time.Duration
+	mode     ThresholdMode
+	mult     float64
 
-	candles []candle.Candle
+	win window
 
 	lastSignalAt time.Time
 }
 
-func NewBreakoutDetector(lookback time.Duration, pct float64, cooldown time.Duration) *BreakoutDetector {
+type BreakoutOption func(*BreakoutDetector)
+
+// WithVolatilityThreshold replaces the fixed pct with mult times the ATR or
+// the return standard deviation of the candles in the lookback window.
+func WithVolatilityThreshold(mode ThresholdMode, mult float64) BreakoutOption {
+	return func(d *BreakoutDetector) {
+		d.mode = mode
+		d.mult = max(mult, 0)
+	}
+}
+
+func NewBreakoutDetector(lookback time.Duration, pct float64, cooldown time.Duration, opts ...BreakoutOption) *BreakoutDetector {
 	if lookback <= 0 {
 		lookback = 5 * time.Minute
 	}
@@ -46,22 +88,35 @@ func NewBreakoutDetector(lookback time.Duration, pct float64, cooldown time.Dura
 	if cooldown < 0 {
 		cooldown = 0
 	}
-	return &BreakoutDetector{lookback: lookback, pct: pct, cooldown: cooldown}
+	d := &BreakoutDetector{lookback: lookback, pct: pct, cooldown: cooldown, mode: ThresholdPct}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
+// Push adds a completed candle and reports whether it broke out of the
+// window before it. A late candle older than the newest one joins the window
+// at its place without a signal.
 func (d *BreakoutDetector) Push(c candle.Candle) (BreakoutEvent, bool) {
-	d.candles = append(d.candles, c)
-	cut := c.End.Add(-d.lookback)
-
-	start := 0
-	for start < len(d.candles) && d.candles[start].End.Before(cut) {
-		start++
+	if cs := d.win.all(); len(cs) > 0 && c.Start.Before(cs[len(cs)-1].Start) {
+		i := sort.Search(len(cs), func(i int) bool { return cs[i].Start.After(c.Start) })
+		cs = append(cs[:i:i], append([]candle.Candle{c}, cs[i:]...)...)
+		d.win.reset(cs)
+		return BreakoutEvent{}, false
 	}
-	if start > 0 {
-		d.candles = d.candles[start:]
+	cut := c.End.Add(-d.lookback)
+	for d.win.len() > 0 && d.win.candles[d.win.head].End.Before(cut) {
+		d.win.popFront()
 	}
+	ev, ok := d.check(c)
+	d.win.push(c)
+	return ev, ok
+}
 
-	if len(d.candles) < 2 {
+// check tests c against the window of the candles before it.
+func (d *BreakoutDetector) check(c candle.Candle) (BreakoutEvent, bool) {
+	if d.win.len() < 1 {
 		return BreakoutEvent{}, false
 	}
 
@@ -71,51 +126,83 @@ func (d *BreakoutDetector) Push(c candle.Candle) (BreakoutEvent, bool) {
 		}
 	}
 
-	high := -math.MaxFloat64
-	low := math.MaxFloat64
-
-	for i := 0; i < len(d.candles)-1; i++ {
-		if d.candles[i].High > high {
-			high = d.candles[i].High
-		}
-		if d.candles[i].Low < low {
-			low = d.candles[i].Low
+	high, low := d.win.high(), d.win.low()
+	upLevel := high * (1 + d.pct)
+	downLevel := low * (1 - d.pct)
+	var vol float64
+	switch d.mode {
+	case ThresholdATR:
+		vol = d.win.atr()
+		upLevel, downLevel = high+d.mult*vol, low-d.mult*vol
+	case ThresholdStdDev:
+		var ok bool
+		if vol, ok = d.win.returnStdDev(); !ok {
+			return BreakoutEvent{}, false
 		}
+		upLevel, downLevel = high*(1+d.mult*vol), low*(1-d.mult*vol)
 	}
 
 	price := c.Close
-	upLevel := high * (1 + d.pct)
-	downLevel := low * (1 - d.pct)
+	var dir BreakoutDirection
+	var level float64
+	switch {
+	case price > upLevel:
+		dir, level = BreakoutUp, high
+	case price < downLevel:
+		dir, level = BreakoutDown, low
+	default:
+		return BreakoutEvent{}, false
+	}
+
+	d.lastSignalAt = c.End
+	ev := BreakoutEvent{
+		Type:      "breakout",
+		Symbol:    c.Symbol,
+		Source:    c.Source,
+		Dir:       dir,
+		Price:     price,
+		Level:     level,
+		Pct:       d.pct,
+		Lookback:  d.lookback.String(),
+		CandleEnd: c.End,
+		Timestamp: c.Timestamp,
+	}
+	if d.mode != ThresholdPct {
+		ev.Pct = 0
+		ev.Mode, ev.Multiplier, ev.Volatility = d.mode, d.mult, vol
+	}
+	return ev, true
+}
+
+// Revise replaces the candle in the window with the same start as c, e.g.
+// after late ticks amended it. Signals already emitted are not re-evaluated.
+func (d *BreakoutDetector) Revise(c candle.Candle) {
+	cs := d.win.all()
+	for i := len(cs) - 1; i >= 0; i-- {
+		if cs[i].Start.Equal(c.Start) {
+			cs[i] = c
+			d.win.reset(cs)
+			return
+		}
+	}
+}
+
+// BreakoutSnapshot is the serializable state of a BreakoutDetector.
+type BreakoutSnapshot struct {
+	Candles      []candle.Candle `json:"candles"`
+	LastSignalAt time.Time       `json:"lastSignalAt"`
+}
+
+func (d *BreakoutDetector) Snapshot() BreakoutSnapshot {
+	return BreakoutSnapshot{
+		Candles:      append([]candle.Candle(nil), d.win.all()...),
+		LastSignalAt: d.lastSignalAt,
+	}
+}
 
-	if price > upLevel {
-		d.lastSignalAt = c.End
-		return BreakoutEvent{
-			Type:      "breakout",
-			Symbol:    c.Symbol,
-			Dir:       BreakoutUp,
-			Price:     price,
-			Level:     high,
-			Pct:       d.pct,
-			Lookback:  d.lookback.String(),
-			CandleEnd: c.End,
-			Timestamp: c.Timestamp,
-		}, true
-	}
-
-	if price < downLevel {
-		d.lastSignalAt = c.End
-		return BreakoutEvent{
-			Type:      "breakout",
-			Symbol:    c.Symbol,
-			Dir:       BreakoutDown,
-			Price:     price,
-			Level:     low,
-			Pct:       d.pct,
-			Lookback:  d.lookback.String(),
-			CandleEnd: c.End,
-			Timestamp: c.Timestamp,
-		}, true
-	}
-
-	return BreakoutEvent{}, false
+// Restore loads the candle window and cooldown from s. Candles outside the
+// lookback are dropped on the next Push.
+func (d *BreakoutDetector) Restore(s BreakoutSnapshot) {
+	d.win.reset(s.Candles)
+	d.lastSignalAt = s.LastSignalAt
 }
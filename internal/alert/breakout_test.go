@@ -0,0 +1,216 @@
+package alert
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+)
+
+var t0 = time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+
+// bar returns the i-th 1s candle with the given range around close.
+func bar(i int, close, halfRange float64) candle.Candle {
+	start := t0.Add(time.Duration(i) * time.Second)
+	return candle.Candle{
+		Symbol: "BTCUSDT",
+		Start:  start,
+		End:    start.Add(time.Second),
+		Open:   close,
+		High:   close + halfRange,
+		Low:    close - halfRange,
+		Close:  close,
+	}
+}
+
+func TestVolatilityThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		halfRange float64
+		wantFire  bool
+	}{
+		{"calm", 0.05, true},     // ATR 0.1, threshold 100.25
+		{"volatile", 0.5, false}, // ATR 1, threshold 101.5
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewBreakoutDetector(time.Minute, 0, 0, WithVolatilityThreshold(ThresholdATR, 1))
+			for i := 0; i < 10; i++ {
+				if _, ok := d.Push(bar(i, 100, tt.halfRange)); ok {
+					t.Fatalf("signal on flat candle %d", i)
+				}
+			}
+			ev, ok := d.Push(bar(10, 100.8, 0))
+			if ok != tt.wantFire {
+				t.Fatalf("fired = %v, want %v", ok, tt.wantFire)
+			}
+			if ok {
+				if ev.Mode != ThresholdATR || ev.Dir != BreakoutUp || math.Abs(ev.Volatility-2*tt.halfRange) > 1e-9 {
+					t.Errorf("event = %+v", ev)
+				}
+			}
+		})
+	}
+}
+
+func TestStdDevThreshold(t *testing.T) {
+	d := NewBreakoutDetector(time.Minute, 0, 0, WithVolatilityThreshold(ThresholdStdDev, 3))
+	closes := []float64{100, 100.1, 100, 100.1, 100, 100.1}
+	for i, c := range closes {
+		if _, ok := d.Push(bar(i, c, 0)); ok {
+			t.Fatalf("signal on candle %d", i)
+		}
+	}
+	ev, ok := d.Push(bar(len(closes), 100.5, 0))
+	if !ok {
+		t.Fatal("expected breakout")
+	}
+	// Returns alternate around +-0.1%, so sigma is about 0.001.
+	if ev.Mode != ThresholdStdDev || math.Abs(ev.Volatility-0.001) > 5e-5 || ev.Level != 100.1 {
+		t.Errorf("event = %+v", ev)
+	}
+
+	// Too few returns in the window: no signal.
+	d = NewBreakoutDetector(time.Minute, 0, 0, WithVolatilityThreshold(ThresholdStdDev, 3))
+	d.Push(bar(0, 100, 0))
+	d.Push(bar(1, 100, 0))
+	if _, ok := d.Push(bar(2, 200, 0)); ok {
+		t.Error("signal without a volatility estimate")
+	}
+}
+
+// walk returns n candles of a seeded random walk with the given spacing.
+// With gaps, every 97th candle follows a gap that evicts much of the window
+// at once.
+func walk(n int, every time.Duration, gaps bool, seed int64) []candle.Candle {
+	rng := rand.New(rand.NewSource(seed))
+	cs := make([]candle.Candle, n)
+	price, start := 100.0, t0
+	for i := range cs {
+		if gaps && i%97 == 96 {
+			start = start.Add(time.Duration(rng.Intn(200)) * every)
+		}
+		open := price
+		price *= 1 + rng.NormFloat64()*0.002
+		cs[i] = candle.Candle{
+			Symbol: "BTCUSDT",
+			Start:  start,
+			End:    start.Add(every),
+			Open:   open,
+			High:   max(open, price) * (1 + rng.Float64()*0.001),
+			Low:    min(open, price) * (1 - rng.Float64()*0.001),
+			Close:  price,
+		}
+		start = start.Add(every)
+	}
+	return cs
+}
+
+func TestBreakoutMatchesScan(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     ThresholdMode
+		pct      float64
+		cooldown time.Duration
+	}{
+		{"pct", ThresholdPct, 0.001, 0},
+		{"pct-cooldown", ThresholdPct, 0.0005, 30 * time.Second},
+		{"atr", ThresholdATR, 0, 0},
+		{"stddev-cooldown", ThresholdStdDev, 0, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewBreakoutDetector(2*time.Minute, tt.pct, tt.cooldown, WithVolatilityThreshold(tt.mode, 1.5))
+			ref := newNaiveBreakout(2*time.Minute, tt.pct, tt.cooldown, tt.mode, 1.5)
+			signals := 0
+			for i, c := range walk(5000, time.Second, true, 1) {
+				got, gotOK := d.Push(c)
+				want, wantOK := ref.Push(c)
+				if gotOK != wantOK {
+					t.Fatalf("candle %d: fired = %v, want %v", i, gotOK, wantOK)
+				}
+				if math.Abs(got.Volatility-want.Volatility) > 1e-9 {
+					t.Fatalf("candle %d: volatility = %v, want %v", i, got.Volatility, want.Volatility)
+				}
+				got.Volatility = want.Volatility
+				if got != want {
+					t.Fatalf("candle %d: event = %+v, want %+v", i, got, want)
+				}
+				if gotOK {
+					signals++
+				}
+
+				switch i % 500 {
+				case 250: // amend the previous candle, as late ticks do
+					prev := ref.candles[len(ref.candles)-2]
+					prev.High *= 1.003
+					prev.Close *= 1.001
+					prev.Revision++
+					ref.candles[len(ref.candles)-2] = prev
+					d.Revise(prev)
+				case 499: // round-trip through a snapshot
+					d = NewBreakoutDetector(2*time.Minute, tt.pct, tt.cooldown, WithVolatilityThreshold(tt.mode, 1.5))
+					d.Restore(snapshotJSON(t, ref.candles, ref.lastSignalAt))
+				}
+			}
+			if signals == 0 {
+				t.Fatal("no signals; the walk does not exercise the detector")
+			}
+		})
+	}
+}
+
+// snapshotJSON builds a snapshot and sends it through JSON like
+// the engine does.
+func snapshotJSON(t *testing.T, cs []candle.Candle, lastSignalAt time.Time) BreakoutSnapshot {
+	t.Helper()
+	b, err := json.Marshal(BreakoutSnapshot{Candles: cs, LastSignalAt: lastSignalAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s BreakoutSnapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// The benchmarks push 5s candles through a 24h lookback, about 17k
+// candles per window.
+func benchmarkBreakout(b *testing.B, push func(candle.Candle) (BreakoutEvent, bool)) {
+	const n = 24 * 60 * 12
+	cs := walk(2*n, 5*time.Second, false, 2)
+	for _, c := range cs[:n] {
+		push(c)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := cs[n+i%n]
+		// Keep time moving forward once the candles run out.
+		if shift := time.Duration(i/n) * n * 5 * time.Second; shift > 0 {
+			c.Start, c.End = c.Start.Add(shift), c.End.Add(shift)
+		}
+		push(c)
+	}
+}
+
+func BenchmarkBreakout(b *testing.B) {
+	for _, mode := range []ThresholdMode{ThresholdPct, ThresholdATR, ThresholdStdDev} {
+		b.Run(string(mode), func(b *testing.B) {
+			d := NewBreakoutDetector(24*time.Hour, 0.001, 0, WithVolatilityThreshold(mode, 2))
+			benchmarkBreakout(b, d.Push)
+		})
+	}
+}
+
+func BenchmarkBreakoutScan(b *testing.B) {
+	for _, mode := range []ThresholdMode{ThresholdPct, ThresholdATR, ThresholdStdDev} {
+		b.Run(string(mode), func(b *testing.B) {
+			d := newNaiveBreakout(24*time.Hour, 0.001, 0, mode, 2)
+			benchmarkBreakout(b, d.Push)
+		})
+	}
+}
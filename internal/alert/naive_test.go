@@ -0,0 +1,142 @@
+package alert
+
+import (
+	"math"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+)
+
+// naiveBreakout is the original BreakoutDetector that rescans the whole
+// window on every candle; the tests check the detector against it.
+type naiveBreakout struct {
+	lookback time.Duration
+	pct      float64
+	cooldown time.Duration
+	mode     ThresholdMode
+	mult     float64
+
+	candles []candle.Candle
+
+	lastSignalAt time.Time
+}
+
+func newNaiveBreakout(lookback time.Duration, pct float64, cooldown time.Duration, mode ThresholdMode, mult float64) *naiveBreakout {
+	return &naiveBreakout{lookback: lookback, pct: pct, cooldown: cooldown, mode: mode, mult: mult}
+}
+
+func (d *naiveBreakout) Push(c candle.Candle) (BreakoutEvent, bool) {
+	d.candles = append(d.candles, c)
+	cut := c.End.Add(-d.lookback)
+
+	start := 0
+	for start < len(d.candles) && d.candles[start].End.Before(cut) {
+		start++
+	}
+	if start > 0 {
+		d.candles = d.candles[start:]
+	}
+
+	if len(d.candles) < 2 {
+		return BreakoutEvent{}, false
+	}
+
+	if d.cooldown > 0 && !d.lastSignalAt.IsZero() {
+		if c.End.Sub(d.lastSignalAt) < d.cooldown {
+			return BreakoutEvent{}, false
+		}
+	}
+
+	high := -math.MaxFloat64
+	low := math.MaxFloat64
+
+	for i := 0; i < len(d.candles)-1; i++ {
+		if d.candles[i].High > high {
+			high = d.candles[i].High
+		}
+		if d.candles[i].Low < low {
+			low = d.candles[i].Low
+		}
+	}
+
+	window := d.candles[:len(d.candles)-1]
+	upLevel := high * (1 + d.pct)
+	downLevel := low * (1 - d.pct)
+	var vol float64
+	switch d.mode {
+	case ThresholdATR:
+		vol = naiveATR(window)
+		upLevel, downLevel = high+d.mult*vol, low-d.mult*vol
+	case ThresholdStdDev:
+		var ok bool
+		if vol, ok = naiveReturnStdDev(window); !ok {
+			return BreakoutEvent{}, false
+		}
+		upLevel, downLevel = high*(1+d.mult*vol), low*(1-d.mult*vol)
+	}
+
+	price := c.Close
+	var dir BreakoutDirection
+	var level float64
+	switch {
+	case price > upLevel:
+		dir, level = BreakoutUp, high
+	case price < downLevel:
+		dir, level = BreakoutDown, low
+	default:
+		return BreakoutEvent{}, false
+	}
+
+	d.lastSignalAt = c.End
+	ev := BreakoutEvent{
+		Type:      "breakout",
+		Symbol:    c.Symbol,
+		Source:    c.Source,
+		Dir:       dir,
+		Price:     price,
+		Level:     level,
+		Pct:       d.pct,
+		Lookback:  d.lookback.String(),
+		CandleEnd: c.End,
+		Timestamp: c.Timestamp,
+	}
+	if d.mode != ThresholdPct {
+		ev.Pct = 0
+		ev.Mode, ev.Multiplier, ev.Volatility = d.mode, d.mult, vol
+	}
+	return ev, true
+}
+
+// naiveATR is the mean true range of cs; the first candle counts its high-low range.
+func naiveATR(cs []candle.Candle) float64 {
+	sum := 0.0
+	for i, c := range cs {
+		tr := c.High - c.Low
+		if i > 0 {
+			prev := cs[i-1].Close
+			tr = max(tr, math.Abs(c.High-prev), math.Abs(c.Low-prev))
+		}
+		sum += tr
+	}
+	return sum / float64(len(cs))
+}
+
+// naiveReturnStdDev is the population standard deviation of the close-to-close
+// returns of cs; it needs at least two returns.
+func naiveReturnStdDev(cs []candle.Candle) (float64, bool) {
+	var n, sum, sumSq float64
+	for i := 1; i < len(cs); i++ {
+		if cs[i-1].Close == 0 {
+			continue
+		}
+		r := cs[i].Close/cs[i-1].Close - 1
+		n++
+		sum += r
+		sumSq += r * r
+	}
+	if n < 2 {
+		return 0, false
+	}
+	mean := sum / n
+	return math.Sqrt(max(sumSq/n-mean*mean, 0)), true
+}
@@ -0,0 +1,94 @@
+package alert
+
+import (
+	"math"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/indicator"
+)
+
+// window is the candle window of a BreakoutDetector. It keeps the high and
+// low in indicator.Extremes and indicator.Stats for the ATR and return standard
+// deviation, so pushing and evicting a candle are amortized O(1) however
+// long the lookback is.
+type window struct {
+	candles []candle.Candle
+	head    int // index of the oldest candle in the window
+
+	ext indicator.Extremes
+
+	// tr and ret hold each candle's true range and return against the
+	// previous candle; the stats cover every candle after the oldest.
+	tr, ret           []float64
+	trStats, retStats indicator.Stats
+}
+
+func (w *window) len() int { return len(w.candles) - w.head }
+
+// all returns the candles in the window, oldest first.
+func (w *window) all() []candle.Candle { return w.candles[w.head:] }
+
+func (w *window) push(c candle.Candle) {
+	tr, ret := c.High-c.Low, math.NaN()
+	if w.len() > 0 {
+		prev := w.candles[len(w.candles)-1].Close
+		tr = indicator.TrueRange(c.High, c.Low, prev)
+		if prev != 0 {
+			ret = c.Close/prev - 1
+		}
+		w.trStats.Add(tr)
+		if !math.IsNaN(ret) {
+			w.retStats.Add(ret)
+		}
+	}
+	w.candles = append(w.candles, c)
+	w.tr = append(w.tr, tr)
+	w.ret = append(w.ret, ret)
+	w.ext.Push(c.High, c.Low)
+}
+
+// popFront evicts the oldest candle. The next candle becomes the oldest, so
+// its range against the evicted one leaves the stats.
+func (w *window) popFront() {
+	w.head++
+	w.ext.PopFront()
+	if w.len() > 0 {
+		w.trStats.Remove(w.tr[w.head])
+		if ret := w.ret[w.head]; !math.IsNaN(ret) {
+			w.retStats.Remove(ret)
+		}
+	}
+	if w.head > len(w.candles)/2 {
+		w.candles = append(w.candles[:0], w.candles[w.head:]...)
+		w.tr = append(w.tr[:0], w.tr[w.head:]...)
+		w.ret = append(w.ret[:0], w.ret[w.head:]...)
+		w.head = 0
+	}
+}
+
+func (w *window) high() float64 { return w.ext.High() }
+func (w *window) low() float64  { return w.ext.Low() }
+
+// atr is the mean true range of the window; the oldest candle counts its
+// high-low range.
+func (w *window) atr() float64 {
+	first := w.candles[w.head]
+	return (first.High - first.Low + w.trStats.Sum()) / float64(w.len())
+}
+
+// returnStdDev is the population standard deviation of the close-to-close
+// returns of the window; it needs at least two returns.
+func (w *window) returnStdDev() (float64, bool) {
+	if w.retStats.Len() < 2 {
+		return 0, false
+	}
+	return w.retStats.StdDev(), true
+}
+
+// reset rebuilds the window from cs.
+func (w *window) reset(cs []candle.Candle) {
+	*w = window{}
+	for _, c := range cs {
+		w.push(c)
+	}
+}
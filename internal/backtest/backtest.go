@@ -28,8 +28,13 @@ type Config struct {
 	StopLossPct   float64
 	TakeProfitPct float64
 
-	EmaFast int
-	EmaSlow int
+	// EmaMode "time" defines the EMAs by EmaFastHalfLife and EmaSlowHalfLife
+	// instead of the EmaFast and EmaSlow candle counts.
+	EmaMode         trend.EMAMode
+	EmaFast         int
+	EmaSlow         int
+	EmaFastHalfLife time.Duration
+	EmaSlowHalfLife time.Duration
 
 	TrendConfirm  int
 	TrendMinDiff  float64
@@ -38,6 +43,10 @@ type Config struct {
 	BreakoutLookback time.Duration
 	BreakoutPct      float64
 	BreakoutCooldown time.Duration
+	// BreakoutMode atr or stddev sets the threshold to BreakoutK times the
+	// window's volatility instead of BreakoutPct.
+	BreakoutMode alert.ThresholdMode
+	BreakoutK    float64
 }
 
 type Trade struct {
@@ -106,7 +115,14 @@ func Run(candles []candle.Candle, cfg Config) (Result, error) {
 	maxDD := 0.0
 
 	det := trend.NewEMACrossoverDetector(cfg.EmaFast, cfg.EmaSlow, cfg.TrendConfirm, cfg.TrendMinDiff, cfg.TrendCooldown)
-	bo := alert.NewBreakoutDetector(cfg.BreakoutLookback, cfg.BreakoutPct, cfg.BreakoutCooldown)
+	if cfg.EmaMode == trend.EMAModeTime {
+		det = trend.NewTimeEMACrossoverDetector(cfg.EmaFastHalfLife, cfg.EmaSlowHalfLife, cfg.TrendConfirm, cfg.TrendMinDiff, cfg.TrendCooldown)
+	}
+	var boOpts []alert.BreakoutOption
+	if cfg.BreakoutMode != "" && cfg.BreakoutMode != alert.ThresholdPct {
+		boOpts = append(boOpts, alert.WithVolatilityThreshold(cfg.BreakoutMode, cfg.BreakoutK))
+	}
+	bo := alert.NewBreakoutDetector(cfg.BreakoutLookback, cfg.BreakoutPct, cfg.BreakoutCooldown, boOpts...)
 
 	trendDir := trend.DirectionUp
 
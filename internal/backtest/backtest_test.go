@@ -0,0 +1,80 @@
+package backtest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/binance"
+	"realtime-market-engine/internal/binance/binancetest"
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/klinecache"
+)
+
+// TestBinanceOffline runs a backtest end to end on klines from the fake
+// Binance, then again from the kline cache alone.
+func TestBinanceOffline(t *testing.T) {
+	srv := binancetest.NewServer()
+	defer srv.Close()
+
+	// A flat hour, then a breakout that runs into the take profit.
+	start := time.Date(2026, 2, 8, 0, 0, 0, 0, time.UTC)
+	var klines []candle.Candle
+	price := 100.0
+	for i := 0; i < 120; i++ {
+		open := start.Add(time.Duration(i) * time.Minute)
+		if i >= 60 {
+			price *= 1.003
+		}
+		klines = append(klines, candle.Candle{
+			Symbol: "BTCUSDT", Start: open, End: open.Add(time.Minute - time.Millisecond),
+			Open: price, High: price * 1.0005, Low: price * 0.9995, Close: price, Volume: 1,
+		})
+	}
+	srv.AddKlines("BTCUSDT", "1m", klines)
+
+	cfg := Config{
+		EmaFast:          5,
+		EmaSlow:          20,
+		BreakoutLookback: 30 * time.Minute,
+		BreakoutPct:      0.001,
+	}
+	end := start.Add(2*time.Hour - time.Minute)
+	dir := t.TempDir()
+	run := func(f klinecache.Fetcher) Result {
+		t.Helper()
+		candles, err := f.FetchKlines(context.Background(), "BTCUSDT", "1m", start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(candles) != len(klines) {
+			t.Fatalf("got %d candles, want %d", len(candles), len(klines))
+		}
+		res, err := Run(candles, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	online := run(klinecache.New(dir, binance.NewKlineFetcher(binance.WithRESTBaseURL(srv.URL())), false))
+	if len(online.Trades) == 0 || online.Trades[0].Side != SideLong || online.Trades[0].Reason != "take_profit" {
+		t.Fatalf("trades = %+v", online.Trades)
+	}
+	if online.FinalEquity <= 1000 {
+		t.Fatalf("final equity = %v, want a profit", online.FinalEquity)
+	}
+
+	requests := len(srv.Requests())
+	offline := run(klinecache.New(dir, nil, true))
+	// Compared as JSON: cached times decode with a different time.Location.
+	got, _ := json.Marshal(offline)
+	want, _ := json.Marshal(online)
+	if string(got) != string(want) {
+		t.Fatalf("offline result differs:\n got %s\nwant %s", got, want)
+	}
+	if requests == 0 || len(srv.Requests()) != requests {
+		t.Fatalf("REST requests: %d online, %d in total", requests, len(srv.Requests()))
+	}
+}
@@ -7,102 +7,213 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"realtime-market-engine/internal/types"
-
-	"github.com/gorilla/websocket"
+	"realtime-market-engine/internal/wsstream"
 )
 
 type aggTradeMessage struct {
-	EventType string `json:"e"`
-	EventTime int64  `json:"E"`
-	Symbol    string `json:"s"`
-	Price     string `json:"p"`
-	TradeTime int64  `json:"T"`
+	EventType    string `json:"e"`
+	EventTime    int64  `json:"E"`
+	Symbol       string `json:"s"`
+	AggTradeID   int64  `json:"a"`
+	Price        string `json:"p"`
+	Quantity     string `json:"q"`
+	FirstTradeID int64  `json:"f"`
+	LastTradeID  int64  `json:"l"`
+	TradeTime    int64  `json:"T"`
+	BuyerIsMaker bool   `json:"m"`
 }
 
-func StartAggTradeListener(ctx context.Context, symbol string) (<-chan types.PriceEvent, error) {
-	s := strings.ToLower(symbol)
-	url := fmt.Sprintf("wss://stream.binance.com:9443/ws/%s@aggTrade", s)
+// combinedMessage wraps every payload delivered on the /stream endpoint.
+type combinedMessage struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
 
-	ch := make(chan types.PriceEvent, 100)
+func StartAggTradeListener(ctx context.Context, symbol string, opts ...Option) (<-chan types.PriceEvent, error) {
+	return StartAggTradeStreams(ctx, []string{symbol}, opts...)
+}
+
+// StartAggTradeStreams subscribes to the aggTrade stream of every symbol over a
+// single combined-stream connection and reconnects with backoff until ctx is done.
+//
+// The last aggregate trade ID of every symbol is remembered. When the stream
+// skips IDs, typically after a reconnect, the missing trades are fetched from
+// the REST API in the background and delivered in order before the live trade,
+// which is held back with the trades of its symbol that follow it until then;
+// duplicates are dropped. Other symbols are not held up by a backfill.
+func StartAggTradeStreams(ctx context.Context, symbols []string, opts ...Option) (<-chan types.PriceEvent, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("at least one symbol required")
+	}
+
+	streams := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			return nil, fmt.Errorf("empty symbol")
+		}
+		streams = append(streams, s+"@aggTrade")
+	}
+	o := newOptions(opts)
+	rest := newRESTClient(o)
+	url := o.wsBaseURL + "/stream?streams=" + strings.Join(streams, "/")
+
+	ch := make(chan types.PriceEvent, 100*len(symbols))
+
+	send := func(ev types.PriceEvent) bool {
+		select {
+		case ch <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	backfill := func(symbol string, fromID, toID int64) []types.PriceEvent {
+		missed, err := fetchAggTrades(ctx, rest, symbol, fromID, toID)
+		report := BackfillEvent{
+			Type:      "backfill",
+			Symbol:    symbol,
+			FromID:    fromID,
+			ToID:      toID,
+			Trades:    len(missed),
+			Timestamp: time.Now(),
+		}
+		if err != nil {
+			report.Error = err.Error()
+			log.Printf("binance backfill %s error: %v", symbol, err)
+		}
+		if o.onBackfill != nil {
+			o.onBackfill(report)
+		}
+		return missed
+	}
 
 	go func() {
+		var (
+			mu     sync.Mutex
+			states = make(map[string]*aggTradeState, len(symbols))
+			wg     sync.WaitGroup
+		)
 		defer close(ch)
-
-		backoff := 200 * time.Millisecond
-		for {
-			if ctx.Err() != nil {
-				return
-			}
-
-			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
-			if err != nil {
-				log.Printf("binance dial error: %v", err)
-				select {
-				case <-time.After(backoff):
-					if backoff < 5*time.Second {
-						backoff *= 2
-					}
-					continue
-				case <-ctx.Done():
+		defer wg.Wait()
+
+		// drain runs on its own goroutine while st has a gap to fill, so the
+		// stream of every symbol keeps being read during the REST calls. It
+		// delivers the held trades in order, backfilling before each gap.
+		drain := func(st *aggTradeState, last int64) {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				held := st.held
+				st.held = nil
+				if len(held) == 0 {
+					st.backfilling = false
+					mu.Unlock()
 					return
 				}
-			}
-
-			backoff = 200 * time.Millisecond
-			log.Printf("connected to Binance aggTrade: %s", symbol)
-
-			readDone := make(chan struct{})
-			go func() {
-				defer close(readDone)
-				defer conn.Close()
-
-				for {
-					_, message, err := conn.ReadMessage()
-					if err != nil {
-						log.Printf("binance read error: %v", err)
+				mu.Unlock()
+
+				for _, ev := range held {
+					if ev.AggTradeID > last+1 {
+						for _, m := range backfill(ev.Symbol, last+1, ev.AggTradeID-1) {
+							if !send(m) {
+								return
+							}
+						}
+					}
+					if !send(ev) {
 						return
 					}
+					last = ev.AggTradeID
+				}
+			}
+		}
 
-					var msg aggTradeMessage
-					if err := json.Unmarshal(message, &msg); err != nil {
-						log.Printf("binance json error: %v", err)
-						continue
-					}
+		wsstream.Run(ctx, o.dialer, url, "binance aggTrade: "+strings.Join(symbols, ","), nil, func(message []byte) bool {
+			var env combinedMessage
+			if err := json.Unmarshal(message, &env); err != nil {
+				log.Printf("binance json error: %v", err)
+				return true
+			}
 
-					price, err := strconv.ParseFloat(msg.Price, 64)
-					if err != nil {
-						log.Printf("binance price parse error: %v", err)
-						continue
-					}
+			var msg aggTradeMessage
+			if err := json.Unmarshal(env.Data, &msg); err != nil {
+				log.Printf("binance json error: %v", err)
+				return true
+			}
 
-					ev := types.PriceEvent{
-						Symbol:    msg.Symbol,
-						Price:     price,
-						Timestamp: time.UnixMilli(msg.TradeTime),
-						Source:    "binance",
-					}
+			ev, err := msg.priceEvent()
+			if err != nil {
+				log.Printf("binance aggTrade parse error: %v", err)
+				return true
+			}
 
-					select {
-					case ch <- ev:
-					case <-ctx.Done():
-						return
-					}
-				}
-			}()
-
-			select {
-			case <-ctx.Done():
-				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-				<-readDone
-				return
-			case <-readDone:
-				continue
+			mu.Lock()
+			st := states[ev.Symbol]
+			if st == nil {
+				st = &aggTradeState{}
+				states[ev.Symbol] = st
 			}
-		}
+			last, seen := st.lastID, st.seen
+			if seen && ev.AggTradeID <= last {
+				mu.Unlock()
+				return true
+			}
+			st.lastID, st.seen = ev.AggTradeID, true
+			switch {
+			case st.backfilling:
+				st.held = append(st.held, ev)
+				mu.Unlock()
+				return true
+			case seen && o.backfill && ev.AggTradeID > last+1:
+				st.backfilling, st.held = true, []types.PriceEvent{ev}
+				mu.Unlock()
+				wg.Add(1)
+				go drain(st, last)
+				return true
+			}
+			mu.Unlock()
+
+			return send(ev)
+		})
 	}()
 
 	return ch, nil
 }
+
+// aggTradeState tracks the aggregate trade IDs of one symbol. While a gap is
+// backfilled, later live trades are held and delivered after it.
+type aggTradeState struct {
+	lastID      int64 // newest ID received from the stream
+	seen        bool
+	backfilling bool
+	held        []types.PriceEvent
+}
+
+func (m aggTradeMessage) priceEvent() (types.PriceEvent, error) {
+	price, err := strconv.ParseFloat(m.Price, 64)
+	if err != nil {
+		return types.PriceEvent{}, fmt.Errorf("price: %w", err)
+	}
+	qty, err := strconv.ParseFloat(m.Quantity, 64)
+	if err != nil {
+		return types.PriceEvent{}, fmt.Errorf("quantity: %w", err)
+	}
+
+	return types.PriceEvent{
+		Symbol:       m.Symbol,
+		Price:        price,
+		Quantity:     qty,
+		Timestamp:    time.UnixMilli(m.TradeTime),
+		Source:       "binance",
+		AggTradeID:   m.AggTradeID,
+		FirstTradeID: m.FirstTradeID,
+		LastTradeID:  m.LastTradeID,
+		BuyerIsMaker: m.BuyerIsMaker,
+	}, nil
+}
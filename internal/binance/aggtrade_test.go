@@ -0,0 +1,135 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/binance/binancetest"
+	"realtime-market-engine/internal/types"
+)
+
+func trade(id int64, price float64, t time.Time) types.PriceEvent {
+	return types.PriceEvent{
+		Symbol:       "BTCUSDT",
+		Price:        price,
+		Quantity:     0.1,
+		Timestamp:    t,
+		AggTradeID:   id,
+		FirstTradeID: id * 10,
+		LastTradeID:  id * 10,
+	}
+}
+
+func TestAggTradeReconnectBackfill(t *testing.T) {
+	srv := binancetest.NewServer()
+	defer srv.Close()
+
+	t0 := time.UnixMilli(1700000000000)
+	var all []types.PriceEvent
+	for i := int64(1); i <= 8; i++ {
+		all = append(all, trade(i, 100+float64(i), t0.Add(time.Duration(i)*time.Second)))
+	}
+	srv.AddAggTrades(all...)
+
+	srv.AddSession(
+		binancetest.AggTrade(all[0]),
+		binancetest.Raw(`{"stream":`),
+		binancetest.AggTrade(all[1]),
+		binancetest.Disconnect(),
+	)
+	// Trades 3-5 were missed while disconnected; trade 2 is replayed by the server.
+	srv.AddSession(
+		binancetest.AggTrade(all[1]),
+		binancetest.AggTrade(all[5]),
+		binancetest.AggTrade(all[6]),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var backfills []BackfillEvent
+	ch, err := StartAggTradeListener(ctx, "BTCUSDT",
+		WithWSBaseURL(srv.WSURL()),
+		WithRESTBaseURL(srv.URL()),
+		WithBackfillHandler(func(bf BackfillEvent) { backfills = append(backfills, bf) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int64
+	for ev := range ch {
+		got = append(got, ev.AggTradeID)
+		if len(got) == 7 {
+			cancel()
+		}
+	}
+
+	want := []int64{1, 2, 3, 4, 5, 6, 7}
+	if len(got) != len(want) {
+		t.Fatalf("got ids %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got ids %v, want %v", got, want)
+		}
+	}
+
+	if len(backfills) != 1 {
+		t.Fatalf("got %d backfills, want 1", len(backfills))
+	}
+	if bf := backfills[0]; bf.FromID != 3 || bf.ToID != 5 || bf.Trades != 3 || bf.Error != "" {
+		t.Errorf("unexpected backfill: %+v", bf)
+	}
+}
+
+func TestAggTradeBackfillDoesNotBlockStream(t *testing.T) {
+	srv := binancetest.NewServer()
+	defer srv.Close()
+	srv.SetLatency("/api/v3/aggTrades", 300*time.Millisecond)
+
+	t0 := time.UnixMilli(1700000000000)
+	var btc []types.PriceEvent
+	for i := int64(1); i <= 6; i++ {
+		btc = append(btc, trade(i, 100+float64(i), t0.Add(time.Duration(i)*time.Second)))
+	}
+	srv.AddAggTrades(btc...)
+	eth := trade(1, 2000, t0.Add(5*time.Second))
+	eth.Symbol = "ETHUSDT"
+
+	// Trades 2-3 are missing; trade 6 arrives while they are backfilled.
+	srv.AddSession(
+		binancetest.AggTrade(btc[0]),
+		binancetest.AggTrade(btc[3]),
+		binancetest.AggTrade(btc[4]),
+		binancetest.AggTrade(eth),
+		binancetest.AggTrade(btc[5]),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch, err := StartAggTradeStreams(ctx, []string{"BTCUSDT", "ETHUSDT"},
+		WithWSBaseURL(srv.WSURL()),
+		WithRESTBaseURL(srv.URL()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for ev := range ch {
+		got = append(got, fmt.Sprintf("%s:%d", ev.Symbol, ev.AggTradeID))
+		if len(got) == 7 {
+			cancel()
+		}
+	}
+
+	// ETHUSDT is not held up by the BTCUSDT backfill, which is delivered in
+	// order before the live trades held meanwhile.
+	want := []string{"BTCUSDT:1", "ETHUSDT:1", "BTCUSDT:2", "BTCUSDT:3", "BTCUSDT:4", "BTCUSDT:5", "BTCUSDT:6"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
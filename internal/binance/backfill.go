@@ -0,0 +1,78 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+// maxBackfillTrades bounds how many aggregate trades a single gap may replay.
+const maxBackfillTrades = 50000
+
+// BackfillEvent reports a gap in the aggTrade stream and how it was filled.
+type BackfillEvent struct {
+	Type      string    `json:"type"`
+	Symbol    string    `json:"symbol"`
+	FromID    int64     `json:"fromId"`
+	ToID      int64     `json:"toId"`
+	Trades    int       `json:"trades"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// fetchAggTrades downloads aggregate trades with IDs in [fromID, toID] in ascending order.
+func fetchAggTrades(ctx context.Context, rest *restClient, symbol string, fromID, toID int64) ([]types.PriceEvent, error) {
+	if toID < fromID {
+		return nil, nil
+	}
+	if toID-fromID+1 > maxBackfillTrades {
+		return nil, fmt.Errorf("gap of %d trades exceeds backfill limit %d", toID-fromID+1, maxBackfillTrades)
+	}
+
+	var out []types.PriceEvent
+	next := fromID
+	for next <= toID {
+		if ctx.Err() != nil {
+			return out, ctx.Err()
+		}
+
+		q := url.Values{}
+		q.Set("symbol", symbol)
+		q.Set("fromId", strconv.FormatInt(next, 10))
+		q.Set("limit", "1000")
+
+		var rows []aggTradeMessage
+		if err := rest.getJSON(ctx, "/api/v3/aggTrades", q.Encode(), &rows); err != nil {
+			return out, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		prev := next
+		for _, r := range rows {
+			if r.AggTradeID < next {
+				continue
+			}
+			if r.AggTradeID > toID {
+				return out, nil
+			}
+			r.Symbol = symbol
+			ev, err := r.priceEvent()
+			if err != nil {
+				return out, err
+			}
+			out = append(out, ev)
+			next = r.AggTradeID + 1
+		}
+		if next == prev {
+			break
+		}
+	}
+
+	return out, nil
+}
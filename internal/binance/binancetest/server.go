@@ -0,0 +1,466 @@
+// Package binancetest provides an in-process fake of the Binance REST and
+// WebSocket endpoints used by the engine, for tests and offline runs.
+package binancetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
+
+	"github.com/gorilla/websocket"
+)
+
+// Step is one scripted action played to a WebSocket connection.
+type Step struct {
+	Frame      []byte
+	Delay      time.Duration
+	Disconnect bool
+}
+
+// Raw sends b verbatim, which allows malformed frames.
+func Raw(b string) Step { return Step{Frame: []byte(b)} }
+
+// JSON sends v encoded as JSON.
+func JSON(v any) Step {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return Step{Frame: b}
+}
+
+// Sleep pauses the script for d.
+func Sleep(d time.Duration) Step { return Step{Delay: d} }
+
+// Disconnect closes the connection abruptly.
+func Disconnect() Step { return Step{Disconnect: true} }
+
+// AggTrade sends ev as an aggTrade payload wrapped in a combined-stream envelope.
+func AggTrade(ev types.PriceEvent) Step {
+	return JSON(combined(strings.ToLower(ev.Symbol)+"@aggTrade", aggTradePayload(ev)))
+}
+
+// Kline sends c as a raw /ws kline payload for interval.
+func Kline(c candle.Candle, interval string) Step {
+	return JSON(map[string]any{
+		"e": "kline",
+		"E": c.Timestamp.UnixMilli(),
+		"s": c.Symbol,
+		"k": map[string]any{
+			"t": c.Start.UnixMilli(),
+			"T": c.End.UnixMilli(),
+			"s": c.Symbol,
+			"i": interval,
+			"o": fmtFloat(c.Open),
+			"h": fmtFloat(c.High),
+			"l": fmtFloat(c.Low),
+			"c": fmtFloat(c.Close),
+			"v": fmtFloat(c.Volume),
+			"q": fmtFloat(c.QuoteVolume),
+			"n": c.Trades,
+			"V": fmtFloat(c.TakerBuyVolume),
+			"x": c.Closed,
+		},
+	})
+}
+
+// DepthUpdate sends a diff-depth payload for symbol covering update IDs [first, final].
+// bids and asks are [price, qty] pairs.
+func DepthUpdate(symbol string, first, final int64, bids, asks [][2]float64) Step {
+	return JSON(combined(strings.ToLower(symbol)+"@depth@100ms", map[string]any{
+		"e": "depthUpdate",
+		"E": time.Now().UnixMilli(),
+		"s": symbol,
+		"U": first,
+		"u": final,
+		"b": fmtLevels(bids),
+		"a": fmtLevels(asks),
+	}))
+}
+
+// DepthSnapshot is a scripted /api/v3/depth response.
+type DepthSnapshot struct {
+	LastUpdateID int64
+	Bids         [][2]float64
+	Asks         [][2]float64
+}
+
+// Failure is a scripted non-2xx REST response.
+type Failure struct {
+	Status     int
+	Code       int
+	Msg        string
+	RetryAfter int // seconds, 0 omits the header
+}
+
+// Server is a scripted fake Binance. REST data is served from the klines and
+// aggTrades added to it; every WebSocket connection plays the next queued
+// session and then stays open until either side closes it.
+type Server struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	klines    map[string][]candle.Candle
+	aggTrades map[string][]types.PriceEvent
+	depth     map[string][]DepthSnapshot
+	sessions  [][]Step
+	failures  map[string][]Failure
+	latency   map[string]time.Duration
+	weight    int
+	paths     []string
+	requests  []string
+	done      chan struct{}
+}
+
+func NewServer() *Server {
+	s := &Server{
+		klines:    make(map[string][]candle.Candle),
+		aggTrades: make(map[string][]types.PriceEvent),
+		depth:     make(map[string][]DepthSnapshot),
+		failures:  make(map[string][]Failure),
+		latency:   make(map[string]time.Duration),
+		done:      make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/klines", s.rest(s.serveKlines))
+	mux.HandleFunc("GET /api/v3/aggTrades", s.rest(s.serveAggTrades))
+	mux.HandleFunc("GET /api/v3/depth", s.rest(s.serveDepth))
+	mux.HandleFunc("GET /ws/", s.serveWS)
+	mux.HandleFunc("GET /stream", s.serveWS)
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// URL is the REST base URL.
+func (s *Server) URL() string { return s.srv.URL }
+
+// WSURL is the WebSocket base URL.
+func (s *Server) WSURL() string { return "ws" + strings.TrimPrefix(s.srv.URL, "http") }
+
+func (s *Server) Close() {
+	close(s.done)
+	s.srv.CloseClientConnections()
+	s.srv.Close()
+}
+
+// AddKlines makes candles available on /api/v3/klines for symbol and interval.
+func (s *Server) AddKlines(symbol, interval string, candles []candle.Candle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := symbol + "|" + interval
+	s.klines[k] = append(s.klines[k], candles...)
+	sort.Slice(s.klines[k], func(i, j int) bool { return s.klines[k][i].Start.Before(s.klines[k][j].Start) })
+}
+
+// AddAggTrades makes trades available on /api/v3/aggTrades for their symbol.
+func (s *Server) AddAggTrades(trades ...types.PriceEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range trades {
+		s.aggTrades[t.Symbol] = append(s.aggTrades[t.Symbol], t)
+	}
+	for sym := range s.aggTrades {
+		ts := s.aggTrades[sym]
+		sort.Slice(ts, func(i, j int) bool { return ts[i].AggTradeID < ts[j].AggTradeID })
+	}
+}
+
+// AddDepthSnapshots queues snapshots served in order on /api/v3/depth for
+// symbol; the last one is repeated once the queue is drained.
+func (s *Server) AddDepthSnapshots(symbol string, snaps ...DepthSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.depth[symbol] = append(s.depth[symbol], snaps...)
+}
+
+// FailNext makes the next len(fs) requests to path (e.g. /api/v3/klines)
+// return the given failures in order.
+func (s *Server) FailNext(path string, fs ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], fs...)
+}
+
+// SetLatency delays every response on path (e.g. /api/v3/aggTrades) by d.
+func (s *Server) SetLatency(path string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency[path] = d
+}
+
+// SetUsedWeight sets the X-MBX-USED-WEIGHT-1M value reported on REST responses.
+func (s *Server) SetUsedWeight(w int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weight = w
+}
+
+// AddSession queues a script for the next WebSocket connection.
+func (s *Server) AddSession(steps ...Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = append(s.sessions, steps)
+}
+
+// Connections returns the request URIs of all WebSocket connections so far.
+func (s *Server) Connections() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.paths...)
+}
+
+// Requests returns the request URIs of all REST calls so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// rest records the request, sets the weight header and plays scripted failures.
+func (s *Server) rest(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.RequestURI())
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", strconv.Itoa(s.weight))
+		var f *Failure
+		if fs := s.failures[r.URL.Path]; len(fs) > 0 {
+			f = &fs[0]
+			s.failures[r.URL.Path] = fs[1:]
+		}
+		delay := s.latency[r.URL.Path]
+		s.mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-s.done:
+				return
+			}
+		}
+
+		if f != nil {
+			if f.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+			}
+			writeError(w, f.Status, f.Code, f.Msg)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) serveKlines(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	symbol := q.Get("symbol")
+	startMs, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+	endMs, err := strconv.ParseInt(q.Get("endTime"), 10, 64)
+	if err != nil {
+		endMs = 1<<63 - 1
+	}
+	limit := queryLimit(q.Get("limit"), 500, 1000)
+
+	s.mu.Lock()
+	all, ok := s.klines[symbol+"|"+q.Get("interval")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusBadRequest, -1121, "Invalid symbol.")
+		return
+	}
+
+	rows := make([][]any, 0, limit)
+	for _, c := range all {
+		if c.Start.UnixMilli() < startMs || c.Start.UnixMilli() > endMs {
+			continue
+		}
+		rows = append(rows, []any{
+			c.Start.UnixMilli(),
+			fmtFloat(c.Open),
+			fmtFloat(c.High),
+			fmtFloat(c.Low),
+			fmtFloat(c.Close),
+			fmtFloat(c.Volume),
+			c.End.UnixMilli(),
+			fmtFloat(c.QuoteVolume),
+			c.Trades,
+			fmtFloat(c.TakerBuyVolume),
+			fmtFloat(c.TakerBuyVolume * c.Close),
+			"0",
+		})
+		if len(rows) == limit {
+			break
+		}
+	}
+	writeJSON(w, rows)
+}
+
+func (s *Server) serveAggTrades(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fromID, _ := strconv.ParseInt(q.Get("fromId"), 10, 64)
+	limit := queryLimit(q.Get("limit"), 500, 1000)
+
+	s.mu.Lock()
+	all, ok := s.aggTrades[q.Get("symbol")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusBadRequest, -1121, "Invalid symbol.")
+		return
+	}
+
+	rows := make([]map[string]any, 0, limit)
+	for _, t := range all {
+		if t.AggTradeID < fromID {
+			continue
+		}
+		p := aggTradePayload(t)
+		delete(p, "e")
+		delete(p, "E")
+		delete(p, "s")
+		rows = append(rows, p)
+		if len(rows) == limit {
+			break
+		}
+	}
+	writeJSON(w, rows)
+}
+
+func (s *Server) serveDepth(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+
+	s.mu.Lock()
+	snaps := s.depth[symbol]
+	var snap DepthSnapshot
+	ok := len(snaps) > 0
+	if ok {
+		snap = snaps[0]
+		if len(snaps) > 1 {
+			s.depth[symbol] = snaps[1:]
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusBadRequest, -1121, "Invalid symbol.")
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"lastUpdateId": snap.LastUpdateID,
+		"bids":         fmtLevels(snap.Bids),
+		"asks":         fmtLevels(snap.Asks),
+	})
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.paths = append(s.paths, r.URL.RequestURI())
+	var steps []Step
+	if len(s.sessions) > 0 {
+		steps = s.sessions[0]
+		s.sessions = s.sessions[1:]
+	}
+	s.mu.Unlock()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, st := range steps {
+		if st.Delay > 0 {
+			select {
+			case <-time.After(st.Delay):
+			case <-closed:
+				return
+			case <-s.done:
+				return
+			}
+		}
+		if st.Disconnect {
+			return
+		}
+		if st.Frame != nil {
+			if err := conn.WriteMessage(websocket.TextMessage, st.Frame); err != nil {
+				return
+			}
+		}
+	}
+
+	select {
+	case <-closed:
+	case <-s.done:
+	}
+}
+
+func aggTradePayload(ev types.PriceEvent) map[string]any {
+	return map[string]any{
+		"e": "aggTrade",
+		"E": ev.Timestamp.UnixMilli(),
+		"s": ev.Symbol,
+		"a": ev.AggTradeID,
+		"p": fmtFloat(ev.Price),
+		"q": fmtFloat(ev.Quantity),
+		"f": ev.FirstTradeID,
+		"l": ev.LastTradeID,
+		"T": ev.Timestamp.UnixMilli(),
+		"m": ev.BuyerIsMaker,
+	}
+}
+
+func combined(stream string, data any) map[string]any {
+	return map[string]any{"stream": stream, "data": data}
+}
+
+func queryLimit(v string, def, max int) int {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return def
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+func fmtLevels(levels [][2]float64) [][2]string {
+	out := make([][2]string, 0, len(levels))
+	for _, l := range levels {
+		out = append(out, [2]string{fmtFloat(l[0]), fmtFloat(l[1])})
+	}
+	return out
+}
+
+func fmtFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": code, "msg": msg})
+}
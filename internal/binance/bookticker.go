@@ -0,0 +1,100 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/types"
+	"realtime-market-engine/internal/wsstream"
+)
+
+type bookTickerMessage struct {
+	UpdateID int64  `json:"u"`
+	Symbol   string `json:"s"`
+	Bid      string `json:"b"`
+	BidQty   string `json:"B"`
+	Ask      string `json:"a"`
+	AskQty   string `json:"A"`
+}
+
+// StartBookTickerStreams streams best bid/ask updates for every symbol over a
+// single combined-stream connection and reconnects with backoff until ctx is done.
+func StartBookTickerStreams(ctx context.Context, symbols []string, opts ...Option) (<-chan types.Quote, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("at least one symbol required")
+	}
+
+	streams := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			return nil, fmt.Errorf("empty symbol")
+		}
+		streams = append(streams, s+"@bookTicker")
+	}
+
+	o := newOptions(opts)
+	url := o.wsBaseURL + "/stream?streams=" + strings.Join(streams, "/")
+
+	ch := make(chan types.Quote, 100*len(symbols))
+
+	go func() {
+		defer close(ch)
+
+		wsstream.Run(ctx, o.dialer, url, "binance bookTicker: "+strings.Join(symbols, ","), nil, func(message []byte) bool {
+			var env combinedMessage
+			if err := json.Unmarshal(message, &env); err != nil {
+				log.Printf("binance json error: %v", err)
+				return true
+			}
+
+			var msg bookTickerMessage
+			if err := json.Unmarshal(env.Data, &msg); err != nil {
+				log.Printf("binance json error: %v", err)
+				return true
+			}
+
+			q, err := msg.quote()
+			if err != nil {
+				log.Printf("binance bookTicker parse error: %v", err)
+				return true
+			}
+
+			select {
+			case ch <- q:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return ch, nil
+}
+
+func (m bookTickerMessage) quote() (types.Quote, error) {
+	var f [4]float64
+	for i, v := range []string{m.Bid, m.BidQty, m.Ask, m.AskQty} {
+		x, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return types.Quote{}, err
+		}
+		f[i] = x
+	}
+
+	return types.Quote{
+		Symbol:    m.Symbol,
+		Bid:       f[0],
+		BidQty:    f[1],
+		Ask:       f[2],
+		AskQty:    f[3],
+		UpdateID:  m.UpdateID,
+		Timestamp: time.Now(),
+		Source:    "binance",
+	}, nil
+}
@@ -0,0 +1,238 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-market-engine/internal/orderbook"
+	"realtime-market-engine/internal/wsstream"
+)
+
+// depthSnapshotLimit is the number of levels requested from /api/v3/depth.
+const depthSnapshotLimit = 1000
+
+type depthMessage struct {
+	EventType     string      `json:"e"`
+	EventTime     int64       `json:"E"`
+	Symbol        string      `json:"s"`
+	FirstUpdateID int64       `json:"U"`
+	FinalUpdateID int64       `json:"u"`
+	Bids          [][2]string `json:"b"`
+	Asks          [][2]string `json:"a"`
+}
+
+type depthSnapshot struct {
+	LastUpdateID int64       `json:"lastUpdateId"`
+	Bids         [][2]string `json:"bids"`
+	Asks         [][2]string `json:"asks"`
+}
+
+// StartDepthStreams maintains a local order book in books for every symbol,
+// following Binance's documented procedure: diffs from <symbol>@depth@100ms
+// are buffered while a /api/v3/depth snapshot is fetched, stale diffs are
+// dropped, and every later diff must continue the update ID sequence. A gap
+// invalidates the book and triggers a fresh snapshot.
+func StartDepthStreams(ctx context.Context, books *orderbook.Books, symbols []string, opts ...Option) error {
+	if len(symbols) == 0 {
+		return fmt.Errorf("at least one symbol required")
+	}
+
+	streams := make([]string, 0, len(symbols))
+	syncers := make(map[string]*depthSyncer, len(symbols))
+	o := newOptions(opts)
+	rest := newRESTClient(o)
+	for _, s := range symbols {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return fmt.Errorf("empty symbol")
+		}
+		streams = append(streams, strings.ToLower(s)+"@depth@100ms")
+		sym := strings.ToUpper(s)
+		syncers[sym] = &depthSyncer{ctx: ctx, rest: rest, symbol: sym, book: books.GetOrCreate(sym)}
+	}
+	url := o.wsBaseURL + "/stream?streams=" + strings.Join(streams, "/")
+
+	go wsstream.Run(ctx, o.dialer, url, "binance depth: "+strings.Join(symbols, ","), nil, func(message []byte) bool {
+		var env combinedMessage
+		if err := json.Unmarshal(message, &env); err != nil {
+			log.Printf("binance json error: %v", err)
+			return true
+		}
+
+		var msg depthMessage
+		if err := json.Unmarshal(env.Data, &msg); err != nil {
+			log.Printf("binance json error: %v", err)
+			return true
+		}
+
+		if s, ok := syncers[msg.Symbol]; ok {
+			s.push(msg)
+		}
+		return ctx.Err() == nil
+	})
+
+	return nil
+}
+
+// depthSyncer applies diffs for one symbol and owns its resync state.
+type depthSyncer struct {
+	ctx    context.Context
+	rest   *restClient
+	symbol string
+	book   *orderbook.Book
+
+	mu       sync.Mutex
+	synced   bool
+	fetching bool
+	buffer   []depthMessage
+}
+
+func (s *depthSyncer) push(msg depthMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.synced {
+		if err := s.apply(msg); err != nil {
+			log.Printf("binance depth %s: %v, resyncing", s.symbol, err)
+			s.synced = false
+			s.book.Invalidate()
+			s.buffer = append(s.buffer[:0], msg)
+			s.startFetch()
+		}
+		return
+	}
+
+	s.buffer = append(s.buffer, msg)
+	s.startFetch()
+}
+
+// apply checks update ID continuity and merges msg into the book.
+// Must be called with s.mu held.
+func (s *depthSyncer) apply(msg depthMessage) error {
+	last := s.book.LastUpdateID()
+	if msg.FinalUpdateID <= last {
+		return nil
+	}
+	if msg.FirstUpdateID > last+1 {
+		return fmt.Errorf("update gap: have %d, next diff starts at %d", last, msg.FirstUpdateID)
+	}
+
+	bids, err := parseLevels(msg.Bids)
+	if err != nil {
+		return err
+	}
+	asks, err := parseLevels(msg.Asks)
+	if err != nil {
+		return err
+	}
+	s.book.Apply(bids, asks, msg.FinalUpdateID, time.UnixMilli(msg.EventTime))
+	return nil
+}
+
+// startFetch requests a snapshot unless one is in flight. Must be called with s.mu held.
+func (s *depthSyncer) startFetch() {
+	if s.fetching {
+		return
+	}
+	s.fetching = true
+	go s.fetchAndSync()
+}
+
+func (s *depthSyncer) fetchAndSync() {
+	backoff := 200 * time.Millisecond
+	for {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		snap, err := fetchDepthSnapshot(s.ctx, s.rest, s.symbol)
+		if err == nil && s.sync(snap) {
+			return
+		}
+		if err != nil {
+			log.Printf("binance depth snapshot %s error: %v", s.symbol, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+			if backoff < 5*time.Second {
+				backoff *= 2
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// sync seeds the book from snap and replays the buffered diffs. It returns
+// false when the snapshot is older than the buffered stream and must be refetched.
+func (s *depthSyncer) sync(snap depthSnapshot) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buffer) > 0 && snap.LastUpdateID < s.buffer[0].FirstUpdateID {
+		return false
+	}
+
+	bids, err := parseLevels(snap.Bids)
+	if err != nil {
+		log.Printf("binance depth snapshot %s: %v", s.symbol, err)
+		return false
+	}
+	asks, err := parseLevels(snap.Asks)
+	if err != nil {
+		log.Printf("binance depth snapshot %s: %v", s.symbol, err)
+		return false
+	}
+	s.book.Reset(bids, asks, snap.LastUpdateID, time.Now())
+
+	for _, msg := range s.buffer {
+		if err := s.apply(msg); err != nil {
+			log.Printf("binance depth %s: %v, resyncing", s.symbol, err)
+			s.book.Invalidate()
+			s.buffer = s.buffer[:0]
+			return false
+		}
+	}
+
+	s.book.MarkSynced()
+	s.buffer = s.buffer[:0]
+	s.synced = true
+	s.fetching = false
+	return true
+}
+
+func fetchDepthSnapshot(ctx context.Context, rest *restClient, symbol string) (depthSnapshot, error) {
+	q := url.Values{}
+	q.Set("symbol", symbol)
+	q.Set("limit", strconv.Itoa(depthSnapshotLimit))
+
+	var snap depthSnapshot
+	if err := rest.getJSON(ctx, "/api/v3/depth", q.Encode(), &snap); err != nil {
+		return depthSnapshot{}, err
+	}
+	return snap, nil
+}
+
+func parseLevels(rows [][2]string) ([]orderbook.Level, error) {
+	out := make([]orderbook.Level, 0, len(rows))
+	for _, r := range rows {
+		p, err := strconv.ParseFloat(r[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("level price: %w", err)
+		}
+		q, err := strconv.ParseFloat(r[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("level qty: %w", err)
+		}
+		out = append(out, orderbook.Level{Price: p, Qty: q})
+	}
+	return out, nil
+}
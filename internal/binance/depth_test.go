@@ -0,0 +1,76 @@
+package binance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/binance/binancetest"
+	"realtime-market-engine/internal/orderbook"
+)
+
+func TestDepthSyncAndResync(t *testing.T) {
+	srv := binancetest.NewServer()
+	defer srv.Close()
+
+	srv.AddDepthSnapshots("BTCUSDT",
+		binancetest.DepthSnapshot{
+			LastUpdateID: 10,
+			Bids:         [][2]float64{{100, 1}, {99, 2}},
+			Asks:         [][2]float64{{101, 1}, {102, 3}},
+		},
+		binancetest.DepthSnapshot{
+			LastUpdateID: 25,
+			Bids:         [][2]float64{{98, 1}},
+			Asks:         [][2]float64{{103, 1}},
+		},
+	)
+	srv.AddSession(
+		// Stale part of this diff (9-10) is already in the snapshot.
+		binancetest.DepthUpdate("BTCUSDT", 9, 11, [][2]float64{{100, 0}, {99.5, 4}}, nil),
+		binancetest.DepthUpdate("BTCUSDT", 12, 13, nil, [][2]float64{{100.5, 2}}),
+		binancetest.Sleep(200*time.Millisecond),
+		// 14-19 are missing: the book must resync from the second snapshot.
+		binancetest.DepthUpdate("BTCUSDT", 20, 21, [][2]float64{{97, 1}}, nil),
+		binancetest.DepthUpdate("BTCUSDT", 24, 26, [][2]float64{{98.5, 2}}, nil),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	books := orderbook.NewBooks()
+	err := StartDepthStreams(ctx, books, []string{"BTCUSDT"},
+		WithWSBaseURL(srv.WSURL()),
+		WithRESTBaseURL(srv.URL()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	book, _ := books.Get("BTCUSDT")
+
+	waitFor(t, ctx, func() bool { return book.LastUpdateID() == 13 })
+	snap := book.Top(5)
+	if len(snap.Bids) != 2 || snap.Bids[0].Price != 99.5 || snap.Bids[0].Qty != 4 {
+		t.Errorf("unexpected bids after sync: %+v", snap.Bids)
+	}
+	if snap.Asks[0].Price != 100.5 || snap.Spread != 1 || snap.Mid != 100 {
+		t.Errorf("unexpected top of book after sync: %+v", snap)
+	}
+
+	waitFor(t, ctx, func() bool { return book.LastUpdateID() == 26 })
+	snap = book.Top(5)
+	if !snap.Synced || len(snap.Bids) != 2 || snap.Bids[0].Price != 98.5 || snap.Bids[1].Price != 98 {
+		t.Errorf("unexpected book after resync: %+v", snap)
+	}
+}
+
+func waitFor(t *testing.T, ctx context.Context, cond func() bool) {
+	t.Helper()
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -2,10 +2,7 @@ package binance
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"strconv"
 	"time"
@@ -16,17 +13,16 @@ import (
 type klineRespRow []any
 
 type KlineFetcher struct {
-	baseURL string
-	hc      *http.Client
+	rest *restClient
 }
 
-func NewKlineFetcher() *KlineFetcher {
-	return &KlineFetcher{
-		baseURL: "https://api.binance.com",
-		hc: &http.Client{
-			Timeout: 20 * time.Second,
-		},
-	}
+func NewKlineFetcher(opts ...Option) *KlineFetcher {
+	return &KlineFetcher{rest: newRESTClient(newOptions(opts))}
+}
+
+// UsedWeight returns the last request weight reported by Binance for the current minute.
+func (f *KlineFetcher) UsedWeight() int {
+	return f.rest.UsedWeight()
 }
 
 func (f *KlineFetcher) FetchKlines(ctx context.Context, symbol, interval string, start, end time.Time) ([]candle.Candle, error) {
@@ -51,34 +47,16 @@ func (f *KlineFetcher) FetchKlines(ctx context.Context, symbol, interval string,
 			return nil, ctx.Err()
 		}
 
-		u, _ := url.Parse(f.baseURL)
-		u.Path = "/api/v3/klines"
-		q := u.Query()
+		q := url.Values{}
 		q.Set("symbol", symbol)
 		q.Set("interval", interval)
 		q.Set("limit", strconv.FormatInt(limit, 10))
 		q.Set("startTime", strconv.FormatInt(startMs, 10))
 		q.Set("endTime", strconv.FormatInt(endMs, 10))
-		u.RawQuery = q.Encode()
-
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := f.hc.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		b, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return nil, fmt.Errorf("binance klines http %d: %s", resp.StatusCode, string(b))
-		}
 
 		var rows []klineRespRow
-		if err := json.Unmarshal(b, &rows); err != nil {
-			return nil, err
+		if err := f.rest.getJSON(ctx, "/api/v3/klines", q.Encode(), &rows); err != nil {
+			return nil, fmt.Errorf("fetch klines %s %s from %s: %w", symbol, interval, time.UnixMilli(startMs).UTC().Format(time.RFC3339), err)
 		}
 		if len(rows) == 0 {
 			break
@@ -116,8 +94,9 @@ func (f *KlineFetcher) FetchKlines(ctx context.Context, symbol, interval string,
 			startT := time.UnixMilli(openMs)
 			endT := time.UnixMilli(closeMs)
 
-			out = append(out, candle.Candle{
+			c := candle.Candle{
 				Symbol:    symbol,
+				Source:    "binance",
 				Start:     startT,
 				End:       endT,
 				Open:      open,
@@ -125,7 +104,15 @@ func (f *KlineFetcher) FetchKlines(ctx context.Context, symbol, interval string,
 				Low:       low,
 				Close:     closeP,
 				Timestamp: endT,
-			})
+				Closed:    endT.Before(time.Now()),
+			}
+			if len(r) >= 10 {
+				c.Volume, _ = toFloat(r[5])
+				c.QuoteVolume, _ = toFloat(r[7])
+				c.Trades, _ = toInt64(r[8])
+				c.TakerBuyVolume, _ = toFloat(r[9])
+			}
+			out = append(out, c)
 			lastCloseMs = closeMs
 		}
 
@@ -142,8 +129,6 @@ func (f *KlineFetcher) FetchKlines(ctx context.Context, symbol, interval string,
 		if len(rows) < int(limit) {
 			break
 		}
-
-		time.Sleep(150 * time.Millisecond)
 	}
 
 	return out, nil
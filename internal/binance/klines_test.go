@@ -0,0 +1,113 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/binance/binancetest"
+	"realtime-market-engine/internal/candle"
+)
+
+func TestFetchKlinesPaginates(t *testing.T) {
+	srv := binancetest.NewServer()
+	defer srv.Close()
+
+	t0 := time.UnixMilli(1700000000000).UTC()
+	var cs []candle.Candle
+	for i := 0; i < 1500; i++ {
+		start := t0.Add(time.Duration(i) * time.Minute)
+		cs = append(cs, candle.Candle{
+			Symbol: "BTCUSDT",
+			Start:  start,
+			End:    start.Add(time.Minute - time.Millisecond),
+			Open:   100, High: 101, Low: 99, Close: 100.5,
+			Volume: 2, Trades: 7,
+		})
+	}
+	srv.AddKlines("BTCUSDT", "1m", cs)
+
+	f := NewKlineFetcher(WithRESTBaseURL(srv.URL()))
+	got, err := f.FetchKlines(context.Background(), "BTCUSDT", "1m", t0, t0.Add(1500*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(cs) {
+		t.Fatalf("got %d candles, want %d", len(got), len(cs))
+	}
+	if len(srv.Requests()) != 2 {
+		t.Errorf("got %d requests, want 2", len(srv.Requests()))
+	}
+	last := got[len(got)-1]
+	if !last.End.Equal(cs[len(cs)-1].End) || last.Volume != 2 || last.Trades != 7 || !last.Closed {
+		t.Errorf("unexpected last candle: %+v", last)
+	}
+}
+
+func TestFetchKlinesRetries(t *testing.T) {
+	srv := binancetest.NewServer()
+	defer srv.Close()
+
+	t0 := time.UnixMilli(1700000000000).UTC()
+	srv.AddKlines("BTCUSDT", "1m", []candle.Candle{
+		{Symbol: "BTCUSDT", Start: t0, End: t0.Add(time.Minute - time.Millisecond), Open: 1, High: 1, Low: 1, Close: 1},
+	})
+	srv.FailNext("/api/v3/klines",
+		binancetest.Failure{Status: http.StatusInternalServerError},
+		binancetest.Failure{Status: http.StatusTooManyRequests, Code: -1003, Msg: "Too many requests", RetryAfter: 1},
+	)
+	srv.SetUsedWeight(42)
+
+	f := NewKlineFetcher(WithRESTBaseURL(srv.URL()), WithRetry(3, 10*time.Millisecond))
+	start := time.Now()
+	got, err := f.FetchKlines(context.Background(), "BTCUSDT", "1m", t0, t0.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d candles, want 1", len(got))
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Retry-After not honoured, finished after %s", elapsed)
+	}
+	if f.UsedWeight() != 42 {
+		t.Errorf("used weight = %d, want 42", f.UsedWeight())
+	}
+}
+
+func TestFetchKlinesTypedErrors(t *testing.T) {
+	srv := binancetest.NewServer()
+	defer srv.Close()
+
+	t0 := time.UnixMilli(1700000000000).UTC()
+	f := NewKlineFetcher(WithRESTBaseURL(srv.URL()), WithRetry(1, time.Millisecond))
+
+	_, err := f.FetchKlines(context.Background(), "NOPEUSDT", "1m", t0, t0.Add(time.Hour))
+	if !errors.Is(err, ErrInvalidSymbol) {
+		t.Errorf("got %v, want ErrInvalidSymbol", err)
+	}
+
+	srv.FailNext("/api/v3/klines",
+		binancetest.Failure{Status: http.StatusTooManyRequests},
+		binancetest.Failure{Status: http.StatusTooManyRequests},
+	)
+	_, err = f.FetchKlines(context.Background(), "NOPEUSDT", "1m", t0, t0.Add(time.Hour))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("got %v, want ErrRateLimited", err)
+	}
+	// An IP ban fails at once instead of waiting out its Retry-After.
+	srv.FailNext("/api/v3/klines",
+		binancetest.Failure{Status: http.StatusTeapot, Code: -1003, Msg: "Way too many requests; IP banned", RetryAfter: 3600},
+	)
+	before := len(srv.Requests())
+	start := time.Now()
+	_, err = f.FetchKlines(context.Background(), "NOPEUSDT", "1m", t0, t0.Add(time.Hour))
+	if !errors.Is(err, ErrIPBanned) {
+		t.Errorf("got %v, want ErrIPBanned", err)
+	}
+	if n := len(srv.Requests()) - before; n != 1 || time.Since(start) > time.Second {
+		t.Errorf("IP ban retried: %d requests in %s", n, time.Since(start))
+	}
+}
@@ -0,0 +1,106 @@
+package binance
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	restBaseURL = "https://api.binance.com"
+	wsBaseURL   = "wss://stream.binance.com:9443"
+)
+
+// Option customises the REST fetchers and stream listeners.
+type Option func(*options)
+
+type options struct {
+	restBaseURL string
+	wsBaseURL   string
+	hc          *http.Client
+	dialer      *websocket.Dialer
+
+	maxRetries   int
+	retryBackoff time.Duration
+	weightLimit  int
+
+	backfill   bool
+	onBackfill func(BackfillEvent)
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		restBaseURL: restBaseURL,
+		wsBaseURL:   wsBaseURL,
+		hc:          &http.Client{Timeout: 20 * time.Second},
+		dialer:      websocket.DefaultDialer,
+		backfill:    true,
+
+		maxRetries:   5,
+		retryBackoff: 500 * time.Millisecond,
+		weightLimit:  6000,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithRESTBaseURL overrides the REST API base URL (default https://api.binance.com).
+func WithRESTBaseURL(u string) Option {
+	return func(o *options) { o.restBaseURL = strings.TrimRight(u, "/") }
+}
+
+// WithWSBaseURL overrides the WebSocket base URL (default wss://stream.binance.com:9443).
+func WithWSBaseURL(u string) Option {
+	return func(o *options) { o.wsBaseURL = strings.TrimRight(u, "/") }
+}
+
+// WithHTTPClient sets the client used for REST requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) {
+		if hc != nil {
+			o.hc = hc
+		}
+	}
+}
+
+// WithDialer sets the dialer used for WebSocket connections.
+func WithDialer(d *websocket.Dialer) Option {
+	return func(o *options) {
+		if d != nil {
+			o.dialer = d
+		}
+	}
+}
+
+// WithRetry sets how many times a failed REST request is retried and the base
+// backoff between attempts (doubled per attempt, with jitter).
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(o *options) {
+		if maxRetries >= 0 {
+			o.maxRetries = maxRetries
+		}
+		if backoff > 0 {
+			o.retryBackoff = backoff
+		}
+	}
+}
+
+// WithWeightLimit sets the per-minute request weight budget used for pacing
+// (default 6000, 0 disables pacing).
+func WithWeightLimit(limit int) Option {
+	return func(o *options) { o.weightLimit = limit }
+}
+
+// WithBackfill enables or disables REST gap backfill after reconnects (enabled by default).
+func WithBackfill(enabled bool) Option {
+	return func(o *options) { o.backfill = enabled }
+}
+
+// WithBackfillHandler registers fn to be called after every backfill attempt.
+func WithBackfillHandler(fn func(BackfillEvent)) Option {
+	return func(o *options) { o.onBackfill = fn }
+}
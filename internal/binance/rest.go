@@ -0,0 +1,208 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRateLimited     = errors.New("binance: rate limited")
+	ErrIPBanned        = errors.New("binance: ip banned")
+	ErrInvalidSymbol   = errors.New("binance: invalid symbol")
+	ErrInvalidInterval = errors.New("binance: invalid interval")
+)
+
+// APIError is a non-2xx REST response. It matches ErrRateLimited, ErrIPBanned,
+// ErrInvalidSymbol and ErrInvalidInterval with errors.Is where applicable.
+type APIError struct {
+	StatusCode int
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Msg != "" {
+		return fmt.Sprintf("binance http %d: code %d: %s", e.StatusCode, e.Code, e.Msg)
+	}
+	return fmt.Sprintf("binance http %d", e.StatusCode)
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusTeapot:
+		return ErrIPBanned
+	case e.Code == -1121:
+		return ErrInvalidSymbol
+	case e.Code == -1120:
+		return ErrInvalidInterval
+	}
+	return nil
+}
+
+// temporary reports whether the request is worth retrying. A 418 is an IP
+// ban whose Retry-After can be hours away, so it is returned at once.
+func (e *APIError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// restClient performs GET requests against the REST API. Transport errors and
+// 5xx responses are retried with jittered exponential backoff, 429 waits for
+// Retry-After and 418 (IP ban) fails with ErrIPBanned. Requests are paced
+// when X-MBX-USED-WEIGHT-1M gets close to the per-minute weight limit.
+type restClient struct {
+	baseURL     string
+	hc          *http.Client
+	maxRetries  int
+	backoff     time.Duration
+	weightLimit int
+
+	mu         sync.Mutex
+	usedWeight int
+	weightAt   time.Time
+}
+
+func newRESTClient(o options) *restClient {
+	return &restClient{
+		baseURL:     o.restBaseURL,
+		hc:          o.hc,
+		maxRetries:  o.maxRetries,
+		backoff:     o.retryBackoff,
+		weightLimit: o.weightLimit,
+	}
+}
+
+// getJSON fetches baseURL+path?query and decodes the body into out.
+func (c *restClient) getJSON(ctx context.Context, path, query string, out any) error {
+	u := c.baseURL + path
+	if query != "" {
+		u += "?" + query
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			wait := c.retryDelay(attempt, lastErr)
+			log.Printf("binance %s retry %d/%d in %s: %v", path, attempt, c.maxRetries, wait, lastErr)
+			if err := sleepCtx(ctx, wait); err != nil {
+				return err
+			}
+		}
+		if err := c.pace(ctx); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.hc.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		b, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		c.trackWeight(resp.Header)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			apiErr := &APIError{StatusCode: resp.StatusCode}
+			_ = json.Unmarshal(b, apiErr)
+			if apiErr.Msg == "" && len(b) > 0 {
+				apiErr.Msg = string(b)
+			}
+			if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+				apiErr.RetryAfter = time.Duration(s) * time.Second
+			}
+			if !apiErr.temporary() {
+				return apiErr
+			}
+			lastErr = apiErr
+			continue
+		}
+
+		return json.Unmarshal(b, out)
+	}
+
+	return lastErr
+}
+
+func (c *restClient) retryDelay(attempt int, lastErr error) time.Duration {
+	var apiErr *APIError
+	if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	d := c.backoff << (attempt - 1)
+	if d > 30*time.Second || d <= 0 {
+		d = 30 * time.Second
+	}
+	// Equal jitter in [d/2, d].
+	return d/2 + rand.N(d/2+1)
+}
+
+// pace blocks until the next minute window when the last reported weight is
+// above 90% of the limit.
+func (c *restClient) pace(ctx context.Context) error {
+	c.mu.Lock()
+	used, at := c.usedWeight, c.weightAt
+	c.mu.Unlock()
+
+	if c.weightLimit <= 0 || used*10 < c.weightLimit*9 {
+		return nil
+	}
+	next := at.Truncate(time.Minute).Add(time.Minute)
+	wait := time.Until(next)
+	if wait <= 0 {
+		return nil
+	}
+	log.Printf("binance used weight %d/%d, pausing %s", used, c.weightLimit, wait.Round(time.Millisecond))
+	return sleepCtx(ctx, wait)
+}
+
+func (c *restClient) trackWeight(h http.Header) {
+	w, err := strconv.Atoi(h.Get("X-MBX-USED-WEIGHT-1M"))
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usedWeight = w
+	c.weightAt = time.Now()
+}
+
+// UsedWeight returns the last X-MBX-USED-WEIGHT-1M value seen.
+func (c *restClient) UsedWeight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usedWeight
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,29 @@
+package binance
+
+import (
+	"context"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
+)
+
+// Source exposes the Binance trade and kline streams as a market.MarketSource.
+type Source struct {
+	opts []Option
+}
+
+func NewSource(opts ...Option) *Source {
+	return &Source{opts: opts}
+}
+
+func (s *Source) Name() string {
+	return "binance"
+}
+
+func (s *Source) Trades(ctx context.Context, symbols []string) (<-chan types.PriceEvent, error) {
+	return StartAggTradeStreams(ctx, symbols, s.opts...)
+}
+
+func (s *Source) Candles(ctx context.Context, symbol, interval string) (<-chan candle.Candle, error) {
+	return StartKlineListener(ctx, symbol, interval, s.opts...)
+}
@@ -1,6 +1,115 @@
 package binance
 
-// connect to Binance WS kline 1m and send PriceEvent into the channel
-func binanceSubscriber(symbol string, interval int) {
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/wsstream"
+)
+
+// klineMessage is the raw /ws/<symbol>@kline_<interval> payload.
+type klineMessage struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	Symbol    string `json:"s"`
+	Kline     struct {
+		StartTime int64  `json:"t"`
+		CloseTime int64  `json:"T"`
+		Symbol    string `json:"s"`
+		Interval  string `json:"i"`
+		Open      string `json:"o"`
+		Close     string `json:"c"`
+		High      string `json:"h"`
+		Low       string `json:"l"`
+		IsClosed  bool   `json:"x"`
+
+		Volume         string `json:"v"`
+		QuoteVolume    string `json:"q"`
+		Trades         int64  `json:"n"`
+		TakerBuyVolume string `json:"V"`
+	} `json:"k"`
+}
+
+// StartKlineListener streams klines for symbol and interval (e.g. "1m").
+// In-progress updates are delivered with Closed=false; the final update of
+// every kline has Closed=true. The connection is re-established with backoff
+// until ctx is done.
+func StartKlineListener(ctx context.Context, symbol, interval string, opts ...Option) (<-chan candle.Candle, error) {
+	s := strings.ToLower(strings.TrimSpace(symbol))
+	if s == "" {
+		return nil, fmt.Errorf("symbol required")
+	}
+	if interval == "" {
+		return nil, fmt.Errorf("interval required")
+	}
+	o := newOptions(opts)
+	url := fmt.Sprintf("%s/ws/%s@kline_%s", o.wsBaseURL, s, interval)
+
+	ch := make(chan candle.Candle, 100)
+
+	go func() {
+		defer close(ch)
+
+		wsstream.Run(ctx, o.dialer, url, "binance kline: "+symbol+" "+interval, nil, func(message []byte) bool {
+			var msg klineMessage
+			if err := json.Unmarshal(message, &msg); err != nil {
+				log.Printf("binance json error: %v", err)
+				return true
+			}
+
+			c, err := msg.candle()
+			if err != nil {
+				log.Printf("binance kline parse error: %v", err)
+				return true
+			}
+
+			select {
+			case ch <- c:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return ch, nil
+}
+
+func (m klineMessage) candle() (candle.Candle, error) {
+	k := m.Kline
+	var f [7]float64
+	for i, v := range []string{k.Open, k.High, k.Low, k.Close, k.Volume, k.QuoteVolume, k.TakerBuyVolume} {
+		if v == "" {
+			continue
+		}
+		x, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return candle.Candle{}, err
+		}
+		f[i] = x
+	}
+
+	return candle.Candle{
+		Symbol:    k.Symbol,
+		Source:    "binance",
+		Start:     time.UnixMilli(k.StartTime),
+		End:       time.UnixMilli(k.CloseTime),
+		Open:      f[0],
+		High:      f[1],
+		Low:       f[2],
+		Close:     f[3],
+		Timestamp: time.UnixMilli(m.EventTime),
+		Closed:    k.IsClosed,
+
+		Volume:         f[4],
+		QuoteVolume:    f[5],
+		Trades:         k.Trades,
+		TakerBuyVolume: f[6],
+	}, nil
 }
@@ -0,0 +1,149 @@
+package binancedata
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
+)
+
+// TradeReader streams aggTrades from dump files in order, as PriceEvents.
+// Rows are:
+//
+//	agg_trade_id,price,quantity,first_trade_id,last_trade_id,transact_time,
+//	is_buyer_maker,is_best_match
+type TradeReader struct {
+	files    []File
+	from, to time.Time
+
+	idx  int
+	rc   io.ReadCloser
+	cr   *csv.Reader
+	line int
+}
+
+// OpenAggTrades returns a reader over the aggTrades files restricted to
+// from <= Timestamp < to (zero bounds are open). Files are opened lazily.
+func OpenAggTrades(files []File, from, to time.Time) (*TradeReader, error) {
+	for _, f := range files {
+		if f.Kind != "aggTrades" {
+			return nil, fmt.Errorf("binancedata: %s is not an aggTrades file", f.Path)
+		}
+	}
+	return &TradeReader{files: files, from: from, to: to}, nil
+}
+
+// Next returns the next trade or io.EOF.
+func (r *TradeReader) Next() (types.PriceEvent, error) {
+	for {
+		if r.cr == nil {
+			if r.idx >= len(r.files) {
+				return types.PriceEvent{}, io.EOF
+			}
+			rc, err := open(r.files[r.idx].Path)
+			if err != nil {
+				return types.PriceEvent{}, err
+			}
+			r.rc, r.cr, r.line = rc, newCSVReader(rc), 0
+			r.idx++
+		}
+
+		rec, err := r.cr.Read()
+		if err == io.EOF {
+			r.closeFile()
+			continue
+		}
+		f := r.files[r.idx-1]
+		if err != nil {
+			return types.PriceEvent{}, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		r.line++
+		if r.line == 1 && isHeader(rec) {
+			continue
+		}
+
+		ev, err := parseAggTrade(rec, f.Symbol)
+		if err != nil {
+			return types.PriceEvent{}, fmt.Errorf("%s line %d: %w", f.Path, r.line, err)
+		}
+		if !r.from.IsZero() && ev.Timestamp.Before(r.from) {
+			continue
+		}
+		if !r.to.IsZero() && !ev.Timestamp.Before(r.to) {
+			continue
+		}
+		return ev, nil
+	}
+}
+
+func (r *TradeReader) Close() error {
+	r.closeFile()
+	r.idx = len(r.files)
+	return nil
+}
+
+func (r *TradeReader) closeFile() {
+	if r.rc != nil {
+		_ = r.rc.Close()
+	}
+	r.rc, r.cr = nil, nil
+}
+
+func parseAggTrade(rec []string, symbol string) (types.PriceEvent, error) {
+	if len(rec) < 7 {
+		return types.PriceEvent{}, fmt.Errorf("want at least 7 columns, got %d", len(rec))
+	}
+	ev := types.PriceEvent{Symbol: symbol, Source: "binance"}
+
+	var err error
+	if ev.AggTradeID, err = parseInt(rec[0]); err != nil {
+		return types.PriceEvent{}, err
+	}
+	if ev.Price, err = parseFloat(rec[1]); err != nil {
+		return types.PriceEvent{}, err
+	}
+	if ev.Quantity, err = parseFloat(rec[2]); err != nil {
+		return types.PriceEvent{}, err
+	}
+	if ev.FirstTradeID, err = parseInt(rec[3]); err != nil {
+		return types.PriceEvent{}, err
+	}
+	if ev.LastTradeID, err = parseInt(rec[4]); err != nil {
+		return types.PriceEvent{}, err
+	}
+	if ev.Timestamp, err = parseTimestamp(rec[5]); err != nil {
+		return types.PriceEvent{}, err
+	}
+	if ev.BuyerIsMaker, err = strconv.ParseBool(strings.TrimSpace(rec[6])); err != nil {
+		return types.PriceEvent{}, err
+	}
+	return ev, nil
+}
+
+// CandlesFromTrades aggregates the remaining trades of r into candles of
+// interval d. All trades must be of one symbol; the last, still open candle
+// is not returned.
+func CandlesFromTrades(r *TradeReader, d time.Duration) ([]candle.Candle, error) {
+	return BarsFromTrades(r, candle.NewTimeBars(d))
+}
+
+// BarsFromTrades builds bars of any type from the remaining trades of r. The
+// last, still open bar is not returned.
+func BarsFromTrades(r *TradeReader, b candle.BarBuilder) ([]candle.Candle, error) {
+	var out []candle.Candle
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b.Push(ev)...)
+	}
+}
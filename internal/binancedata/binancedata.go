@@ -0,0 +1,141 @@
+// Package binancedata reads the public market data dumps published on
+// data.binance.vision: daily and monthly zipped CSVs of klines and aggTrades,
+// e.g. BTCUSDT-1m-2024-01-01.zip or BTCUSDT-aggTrades-2024-01.zip.
+package binancedata
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File describes a dump file by its name.
+type File struct {
+	Path   string
+	Symbol string
+	Kind   string // "aggTrades" or a kline interval such as "1m"
+}
+
+// ParseName extracts symbol and kind from a dump file name such as
+// BTCUSDT-1m-2024-01-01.zip.
+func ParseName(path string) (File, error) {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(strings.TrimSuffix(base, ".zip"), ".csv")
+	parts := strings.Split(base, "-")
+	if len(parts) < 3 {
+		return File{}, fmt.Errorf("binancedata: unrecognised file name %q", filepath.Base(path))
+	}
+	return File{Path: path, Symbol: strings.ToUpper(parts[0]), Kind: parts[1]}, nil
+}
+
+// Glob expands the patterns and returns the matching dump files sorted by
+// name, which for a single symbol and kind is chronological order.
+func Glob(patterns ...string) ([]File, error) {
+	var out []File
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("binancedata: no files match %q", pattern)
+		}
+		for _, m := range matches {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			f, err := ParseName(m)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return filepath.Base(out[i].Path) < filepath.Base(out[j].Path) })
+	return out, nil
+}
+
+// open returns the CSV content of a .zip (first .csv entry) or plain .csv file.
+func open(path string) (io.ReadCloser, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		return os.Open(path)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if !strings.EqualFold(filepath.Ext(f.Name), ".csv") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			_ = zr.Close()
+			return nil, err
+		}
+		return &zipEntry{ReadCloser: rc, zr: zr}, nil
+	}
+	_ = zr.Close()
+	return nil, fmt.Errorf("binancedata: %s contains no csv file", path)
+}
+
+type zipEntry struct {
+	io.ReadCloser
+	zr *zip.ReadCloser
+}
+
+func (z *zipEntry) Close() error {
+	err := z.ReadCloser.Close()
+	if cerr := z.zr.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func newCSVReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	return cr
+}
+
+// isHeader reports whether rec is a header row. Older dumps have none, newer
+// ones start with e.g. "open_time,open,high,...".
+func isHeader(rec []string) bool {
+	if len(rec) == 0 {
+		return false
+	}
+	_, err := strconv.ParseInt(strings.TrimSpace(rec[0]), 10, 64)
+	return err != nil
+}
+
+// parseTimestamp converts a dump timestamp to time. Spot dumps switched from
+// milliseconds to microseconds in 2025; both are accepted.
+func parseTimestamp(s string) (time.Time, error) {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp %q: %w", s, err)
+	}
+	if v >= 1e14 {
+		return time.UnixMicro(v), nil
+	}
+	return time.UnixMilli(v), nil
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+func parseInt(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+}
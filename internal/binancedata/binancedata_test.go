@@ -0,0 +1,156 @@
+package binancedata
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeZip(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(name + ".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, s := range []string{"1735689600000", "1735689600000000"} {
+		got, err := parseTimestamp(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("parseTimestamp(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestLoadKlines(t *testing.T) {
+	dir := t.TempDir()
+	// 2024 dumps use milliseconds and have no header.
+	writeZip(t, dir, "BTCUSDT-1m-2024-12-31",
+		"1735689480000,100.0,101.0,99.5,100.5,2.5,1735689539999,251.0,10,1.5,150.0,0\n"+
+			"1735689540000,100.5,102.0,100.0,101.5,3.0,1735689599999,303.0,12,2.0,202.0,0\n")
+	// 2025 dumps use microseconds and may start with a header; the first row
+	// repeats the last candle of the previous file.
+	writeZip(t, dir, "BTCUSDT-1m-2025-01-01",
+		"open_time,open,high,low,close,volume,close_time,quote_volume,count,taker_buy_volume,taker_buy_quote_volume,ignore\n"+
+			"1735689540000000,100.5,102.0,100.0,101.5,3.0,1735689599999999,303.0,12,2.0,202.0,0\n"+
+			"1735689600000000,101.5,103.0,101.0,102.5,4.0,1735689659999999,410.0,15,2.5,256.0,0\n")
+
+	files, err := Glob(filepath.Join(dir, "*.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Symbol != "BTCUSDT" || files[0].Kind != "1m" {
+		t.Fatalf("Glob = %+v", files)
+	}
+
+	candles, err := LoadKlines(files, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 3 {
+		t.Fatalf("got %d candles, want 3", len(candles))
+	}
+	for i := 1; i < len(candles); i++ {
+		if got := candles[i].Start.Sub(candles[i-1].Start); got != time.Minute {
+			t.Fatalf("candle %d starts %v after previous, want 1m", i, got)
+		}
+	}
+	last := candles[2]
+	if last.Close != 102.5 || last.Volume != 4 || last.Trades != 15 || last.TakerBuyVolume != 2.5 || !last.Closed {
+		t.Fatalf("last candle = %+v", last)
+	}
+
+	from := time.UnixMilli(1735689540000)
+	candles, err = LoadKlines(files, from, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 1 || !candles[0].Start.Equal(from) {
+		t.Fatalf("filtered candles = %+v", candles)
+	}
+}
+
+func TestAggTrades(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, dir, "ETHUSDT-aggTrades-2024-12-31",
+		"1,3000.10,0.5,10,12,1735689599000,true,true\n"+
+			"2,3000.20,0.1,13,13,1735689599500,false,true\n")
+	writeZip(t, dir, "ETHUSDT-aggTrades-2025-01-01",
+		"agg_trade_id,price,quantity,first_trade_id,last_trade_id,transact_time,is_buyer_maker,is_best_match\n"+
+			"3,3001.00,1.0,14,15,1735689600100000,false,true\n"+
+			"4,3002.00,2.0,16,16,1735689601000000,true,true\n")
+
+	files, err := Glob(filepath.Join(dir, "*-2025-*.zip"), filepath.Join(dir, "*-2024-*.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := OpenAggTrades(files, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var ids []int64
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Symbol != "ETHUSDT" || ev.Source != "binance" {
+			t.Fatalf("event = %+v", ev)
+		}
+		if ev.AggTradeID == 3 {
+			if !ev.Timestamp.Equal(time.UnixMilli(1735689600100)) || ev.TradeCount() != 2 || ev.BuyerIsMaker {
+				t.Fatalf("trade 3 = %+v", ev)
+			}
+		}
+		ids = append(ids, ev.AggTradeID)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[3] != 4 {
+		t.Fatalf("ids = %v, want 1..4 in order", ids)
+	}
+
+	r, err = OpenAggTrades(files, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	candles, err := CandlesFromTrades(r, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Trades fall into the seconds :59, :00 and :01; the last stays open.
+	if len(candles) != 2 || candles[0].Open != 3000.10 || candles[0].Close != 3000.20 {
+		t.Fatalf("candles = %+v", candles)
+	}
+
+	if _, err := OpenAggTrades([]File{{Path: "x", Kind: "1m"}}, time.Time{}, time.Time{}); err == nil {
+		t.Fatal("OpenAggTrades accepted a kline file")
+	}
+}
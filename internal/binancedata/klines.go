@@ -0,0 +1,129 @@
+package binancedata
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+)
+
+// ParseKlines reads kline rows:
+//
+//	open_time,open,high,low,close,volume,close_time,quote_volume,count,
+//	taker_buy_volume,taker_buy_quote_volume,ignore
+func ParseKlines(r io.Reader, symbol string) ([]candle.Candle, error) {
+	cr := newCSVReader(r)
+	var out []candle.Candle
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && isHeader(rec) {
+			continue
+		}
+		c, err := parseKline(rec, symbol)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		out = append(out, c)
+	}
+}
+
+func parseKline(rec []string, symbol string) (candle.Candle, error) {
+	if len(rec) < 7 {
+		return candle.Candle{}, fmt.Errorf("want at least 7 columns, got %d", len(rec))
+	}
+	start, err := parseTimestamp(rec[0])
+	if err != nil {
+		return candle.Candle{}, err
+	}
+	end, err := parseTimestamp(rec[6])
+	if err != nil {
+		return candle.Candle{}, err
+	}
+
+	c := candle.Candle{
+		Symbol:    symbol,
+		Source:    "binance",
+		Start:     start,
+		End:       end,
+		Timestamp: end,
+		Closed:    true,
+	}
+	for i, dst := range []*float64{&c.Open, &c.High, &c.Low, &c.Close} {
+		if *dst, err = parseFloat(rec[1+i]); err != nil {
+			return candle.Candle{}, err
+		}
+	}
+	if len(rec) >= 10 {
+		if c.Volume, err = parseFloat(rec[5]); err != nil {
+			return candle.Candle{}, err
+		}
+		if c.QuoteVolume, err = parseFloat(rec[7]); err != nil {
+			return candle.Candle{}, err
+		}
+		if c.Trades, err = parseInt(rec[8]); err != nil {
+			return candle.Candle{}, err
+		}
+		if c.TakerBuyVolume, err = parseFloat(rec[9]); err != nil {
+			return candle.Candle{}, err
+		}
+	}
+	return c, nil
+}
+
+// ReadKlines reads one kline dump file.
+func ReadKlines(f File) ([]candle.Candle, error) {
+	rc, err := open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	out, err := ParseKlines(rc, f.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return out, nil
+}
+
+// LoadKlines reads the kline files and returns the candles whose open time
+// is within [start, end], sorted and without duplicates. Zero bounds are open.
+func LoadKlines(files []File, start, end time.Time) ([]candle.Candle, error) {
+	var out []candle.Candle
+	for _, f := range files {
+		if f.Kind == "aggTrades" || f.Kind == "trades" {
+			return nil, fmt.Errorf("binancedata: %s is not a kline file", f.Path)
+		}
+		cs, err := ReadKlines(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cs {
+			if !start.IsZero() && c.Start.Before(start) {
+				continue
+			}
+			if !end.IsZero() && c.Start.After(end) {
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	dedup := out[:0]
+	for _, c := range out {
+		if n := len(dedup); n > 0 && dedup[n-1].Start.Equal(c.Start) && dedup[n-1].Symbol == c.Symbol {
+			dedup[n-1] = c
+			continue
+		}
+		dedup = append(dedup, c)
+	}
+	return dedup, nil
+}
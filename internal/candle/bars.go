@@ -0,0 +1,302 @@
+package candle
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+// BarBuilder turns a tick stream into bars. Every bar type produces Candle
+// values, so consumers such as the breakout detector and the backtester
+// take any of them unchanged.
+type BarBuilder interface {
+	// Push adds a tick and returns the bars it completed, oldest first.
+	Push(ev types.PriceEvent) []Candle
+	// Flush completes the open bar, if any, e.g. at the end of the data.
+	Flush() (Candle, bool)
+}
+
+// ParseBars builds a BarBuilder from a spec: "time" for time bars of
+// interval, or "tick:N", "volume:V", "dollar:D" or "renko:BOX". A "ha:"
+// prefix applies the Heikin-Ashi transform, e.g. "ha:time" or "ha:volume:100".
+// N is a whole number of trades and BOX is at least MinRenkoBox.
+func ParseBars(spec string, interval time.Duration) (BarBuilder, error) {
+	if rest, ok := strings.CutPrefix(spec, "ha:"); ok {
+		b, err := ParseBars(rest, interval)
+		if err != nil {
+			return nil, err
+		}
+		return NewHeikinAshiBars(b), nil
+	}
+	if spec == "time" {
+		return NewTimeBars(interval), nil
+	}
+
+	kind, arg, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("candle: invalid bar spec %q", spec)
+	}
+	x, err := strconv.ParseFloat(arg, 64)
+	if err != nil || x <= 0 {
+		return nil, fmt.Errorf("candle: invalid %s bar size %q", kind, arg)
+	}
+	switch kind {
+	case "tick":
+		if x != math.Trunc(x) || x > math.MaxInt64 {
+			return nil, fmt.Errorf("candle: tick bar size %q is not a whole number of trades", arg)
+		}
+		return NewTickBars(int64(x)), nil
+	case "volume":
+		return NewVolumeBars(x), nil
+	case "dollar":
+		return NewDollarBars(x), nil
+	case "renko":
+		if x < MinRenkoBox {
+			return nil, fmt.Errorf("candle: renko box %q is below %g", arg, MinRenkoBox)
+		}
+		return NewRenkoBars(x), nil
+	default:
+		return nil, fmt.Errorf("candle: unknown bar type %q", kind)
+	}
+}
+
+// TimeBars adapts Aggregator to BarBuilder.
+type TimeBars struct {
+	agg *Aggregator
+}
+
+func NewTimeBars(interval time.Duration, opts ...AggregatorOption) *TimeBars {
+	return &TimeBars{agg: NewAggregator(interval, opts...)}
+}
+
+func (b *TimeBars) Push(ev types.PriceEvent) []Candle {
+	out := b.agg.Advance(ev.Timestamp)
+	if c, ok := b.agg.Push(ev); ok && c.Revision == 0 {
+		out = append(out, c)
+	}
+	return out
+}
+
+func (b *TimeBars) Flush() (Candle, bool) {
+	return b.agg.Flush()
+}
+
+// ThresholdBars close once a running measure of the open bar, such as its
+// trade count or volume, reaches a threshold. The tick crossing the threshold
+// belongs to the bar it closes; ticks are never split. Start and End are the
+// timestamps of the first and last tick of the bar.
+type ThresholdBars struct {
+	label     string
+	threshold float64
+	measure   func(*Candle) float64
+
+	has     bool
+	current Candle
+}
+
+// NewTickBars closes a bar every n trades (aggregated trades count as many).
+func NewTickBars(n int64) *ThresholdBars {
+	return &ThresholdBars{label: fmt.Sprintf("tick:%d", n), threshold: float64(n), measure: func(c *Candle) float64 { return float64(c.Trades) }}
+}
+
+// NewVolumeBars closes a bar every v of base volume.
+func NewVolumeBars(v float64) *ThresholdBars {
+	return &ThresholdBars{label: "volume:" + formatSize(v), threshold: v, measure: func(c *Candle) float64 { return c.Volume }}
+}
+
+// NewDollarBars closes a bar every d of quote notional.
+func NewDollarBars(d float64) *ThresholdBars {
+	return &ThresholdBars{label: "dollar:" + formatSize(d), threshold: d, measure: func(c *Candle) float64 { return c.QuoteVolume }}
+}
+
+func (b *ThresholdBars) Push(ev types.PriceEvent) []Candle {
+	if !b.has {
+		b.current = newCandle(ev, ev.Timestamp, ev.Timestamp)
+		b.current.Interval = b.label
+		b.has = true
+	} else {
+		b.current.add(ev)
+		b.current.End = ev.Timestamp
+	}
+	if b.measure(&b.current) < b.threshold {
+		return nil
+	}
+	c, _ := b.Flush()
+	return []Candle{c}
+}
+
+func (b *ThresholdBars) Flush() (Candle, bool) {
+	if !b.has {
+		return Candle{}, false
+	}
+	c := b.current
+	c.Closed = true
+	b.has = false
+	b.current = Candle{}
+	return c, true
+}
+
+const (
+	// MinRenkoBox is the smallest box ParseBars accepts, the finest price
+	// increment of the venues; smaller boxes approach the float64 step at
+	// the price and a modest move would make millions of bricks.
+	MinRenkoBox = 1e-8
+
+	// maxRenkoBricks bounds the bricks a single tick completes.
+	maxRenkoBricks = 1000
+)
+
+// RenkoBars emit a brick each time the price moves a box beyond the last
+// brick: one box to continue the trend, two boxes from the last close to
+// reverse it. Bricks span exactly one box, so High and Low are the brick
+// bounds; the volume traded since the previous brick goes to the first brick
+// a tick completes. A tick moving more than maxRenkoBricks boxes only emits
+// the last maxRenkoBricks of its bricks.
+type RenkoBars struct {
+	box   float64
+	label string
+
+	has   bool
+	open  float64 // bounds of the last brick; equal before the first brick
+	close float64
+
+	// acc accumulates the volume of the ticks since the last brick.
+	pending bool
+	acc     Candle
+	start   time.Time
+}
+
+func NewRenkoBars(box float64) *RenkoBars {
+	return &RenkoBars{box: box, label: "renko:" + formatSize(box)}
+}
+
+func (b *RenkoBars) Push(ev types.PriceEvent) []Candle {
+	if !b.has {
+		b.open, b.close = ev.Price, ev.Price
+		b.has = true
+	}
+	if !b.pending {
+		b.acc = Candle{Symbol: ev.Symbol, Source: ev.Source}
+		b.start = ev.Timestamp
+		b.pending = true
+	}
+	b.acc.addVolume(ev)
+
+	hi, lo := math.Max(b.open, b.close), math.Min(b.open, b.close)
+	switch {
+	case ev.Price >= hi+b.box:
+		return b.bricks(hi, b.box, math.Floor((ev.Price-hi)/b.box), ev)
+	case ev.Price <= lo-b.box:
+		return b.bricks(lo, -b.box, math.Floor((lo-ev.Price)/b.box), ev)
+	}
+	return nil
+}
+
+// bricks completes n bricks of step from the level from, keeping the last
+// maxRenkoBricks. It stops early should step vanish against the level.
+func (b *RenkoBars) bricks(from, step, n float64, ev types.PriceEvent) []Candle {
+	n = max(n, 1) // the caller saw at least one box; rounding may say otherwise
+	if n > maxRenkoBricks {
+		from += (n - maxRenkoBricks) * step
+		n = maxRenkoBricks
+	}
+	out := make([]Candle, 0, int(n))
+	for k := range int(n) {
+		open, close := from+float64(k)*step, from+float64(k+1)*step
+		if open == close {
+			break
+		}
+		out = append(out, b.brick(open, close, ev))
+	}
+	return out
+}
+
+func (b *RenkoBars) brick(open, close float64, ev types.PriceEvent) Candle {
+	c := b.acc
+	c.Start = b.start
+	c.End = ev.Timestamp
+	c.Open, c.Close = open, close
+	c.High, c.Low = math.Max(open, close), math.Min(open, close)
+	c.Timestamp = ev.Timestamp
+	c.Closed = true
+	c.Interval = b.label
+
+	b.open, b.close = open, close
+	b.acc = Candle{Symbol: ev.Symbol, Source: ev.Source}
+	b.start = ev.Timestamp
+	b.pending = false
+	return c
+}
+
+// Flush returns nothing: a brick only exists once the price moved a full box.
+func (b *RenkoBars) Flush() (Candle, bool) {
+	return Candle{}, false
+}
+
+// HeikinAshi turns a series of candles into Heikin-Ashi candles.
+type HeikinAshi struct {
+	has         bool
+	open, close float64
+}
+
+// Next returns the Heikin-Ashi candle of c given the candles passed before.
+// Volume and timing fields are kept.
+func (h *HeikinAshi) Next(c Candle) Candle {
+	haClose := (c.Open + c.High + c.Low + c.Close) / 4
+	haOpen := (c.Open + c.Close) / 2
+	if h.has {
+		haOpen = (h.open + h.close) / 2
+	}
+	h.has, h.open, h.close = true, haOpen, haClose
+
+	c.High = math.Max(c.High, math.Max(haOpen, haClose))
+	c.Low = math.Min(c.Low, math.Min(haOpen, haClose))
+	c.Open, c.Close = haOpen, haClose
+	return c
+}
+
+// HeikinAshiCandles returns the Heikin-Ashi transform of cs.
+func HeikinAshiCandles(cs []Candle) []Candle {
+	var h HeikinAshi
+	out := make([]Candle, len(cs))
+	for i, c := range cs {
+		out[i] = h.Next(c)
+	}
+	return out
+}
+
+// HeikinAshiBars applies the Heikin-Ashi transform to the bars of another builder.
+type HeikinAshiBars struct {
+	b  BarBuilder
+	ha HeikinAshi
+}
+
+func NewHeikinAshiBars(b BarBuilder) *HeikinAshiBars {
+	return &HeikinAshiBars{b: b}
+}
+
+func (b *HeikinAshiBars) Push(ev types.PriceEvent) []Candle {
+	out := b.b.Push(ev)
+	for i, c := range out {
+		out[i] = b.ha.Next(c)
+	}
+	return out
+}
+
+// Flush completes the open bar. The transform state advances, so call it
+// only once no more ticks follow.
+func (b *HeikinAshiBars) Flush() (Candle, bool) {
+	c, ok := b.b.Flush()
+	if !ok {
+		return Candle{}, false
+	}
+	return b.ha.Next(c), true
+}
+
+func formatSize(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
@@ -0,0 +1,152 @@
+package candle
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+func barTicks(prices ...float64) []types.PriceEvent {
+	t0 := time.Unix(1700000000, 0)
+	out := make([]types.PriceEvent, len(prices))
+	for i, p := range prices {
+		out[i] = types.PriceEvent{Symbol: "BTCUSDT", Price: p, Quantity: 2, Timestamp: t0.Add(time.Duration(i) * time.Second)}
+	}
+	return out
+}
+
+func pushAll(b BarBuilder, ticks []types.PriceEvent) []Candle {
+	var out []Candle
+	for _, ev := range ticks {
+		out = append(out, b.Push(ev)...)
+	}
+	return out
+}
+
+func TestThresholdBars(t *testing.T) {
+	ticks := barTicks(100, 101, 99, 102, 103, 98, 97)
+
+	tests := []struct {
+		name   string
+		b      BarBuilder
+		closes []float64
+		vols   []float64
+	}{
+		{"tick", NewTickBars(3), []float64{99, 98}, []float64{6, 6}},
+		{"volume", NewVolumeBars(5), []float64{99, 98}, []float64{6, 6}},
+		{"dollar", NewDollarBars(400), []float64{101, 102, 98}, []float64{4, 4, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pushAll(tt.b, ticks)
+			if len(got) != len(tt.closes) {
+				t.Fatalf("got %d bars, want %d: %+v", len(got), len(tt.closes), got)
+			}
+			for i, c := range got {
+				if c.Close != tt.closes[i] || c.Volume != tt.vols[i] || !c.Closed {
+					t.Errorf("bar %d = %+v, want close %v volume %v", i, c, tt.closes[i], tt.vols[i])
+				}
+				if !c.End.Equal(c.Timestamp) || c.Start.After(c.End) {
+					t.Errorf("bar %d spans %s..%s", i, c.Start, c.End)
+				}
+			}
+			if c, ok := tt.b.Flush(); !ok || c.Close != 97 {
+				t.Errorf("flushed bar = %+v, %v", c, ok)
+			}
+		})
+	}
+}
+
+func TestRenkoBars(t *testing.T) {
+	// Up 2 boxes, a pullback short of a reversal, then a reversal.
+	got := pushAll(NewRenkoBars(10), barTicks(100, 115, 121, 105, 99, 89))
+	want := [][2]float64{{100, 110}, {110, 120}, {110, 100}, {100, 90}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d bricks, want %d: %+v", len(got), len(want), got)
+	}
+	for i, c := range got {
+		if c.Open != want[i][0] || c.Close != want[i][1] {
+			t.Errorf("brick %d = %v..%v, want %v..%v", i, c.Open, c.Close, want[i][0], want[i][1])
+		}
+		if c.High != math.Max(c.Open, c.Close) || c.Low != math.Min(c.Open, c.Close) {
+			t.Errorf("brick %d high/low = %v/%v", i, c.High, c.Low)
+		}
+	}
+	// Volume of the ticks up to the first brick, none for the second brick
+	// completed by the same tick.
+	if got[0].Volume != 4 || got[1].Volume != 2 {
+		t.Errorf("brick volumes = %v, %v", got[0].Volume, got[1].Volume)
+	}
+
+	// A gap spanning several boxes emits all of them at once.
+	got = pushAll(NewRenkoBars(1), barTicks(10, 13.5))
+	if len(got) != 3 || got[2].Close != 13 || got[1].Volume != 0 || got[0].Volume != 4 {
+		t.Errorf("gap bricks = %+v", got)
+	}
+}
+
+func TestRenkoBarsLargeMove(t *testing.T) {
+	// A million boxes in one tick: only the last maxRenkoBricks are built,
+	// ending where the full run would.
+	b := NewRenkoBars(0.001)
+	got := pushAll(b, barTicks(1000, 2000))
+	if len(got) != maxRenkoBricks {
+		t.Fatalf("got %d bricks, want %d", len(got), maxRenkoBricks)
+	}
+	if last := got[len(got)-1]; math.Abs(last.Close-2000) > 1e-6 || got[0].Volume != 4 {
+		t.Errorf("last brick = %+v, first volume %v", last, got[0].Volume)
+	}
+	if more := b.Push(barTicks(2000)[0]); len(more) != 0 {
+		t.Errorf("%d more bricks at the same price", len(more))
+	}
+
+	// A box below the float step at the price ends instead of looping.
+	if got := pushAll(NewRenkoBars(1e-12), barTicks(70000, 70001)); len(got) > maxRenkoBricks {
+		t.Errorf("got %d bricks", len(got))
+	}
+}
+
+func TestHeikinAshi(t *testing.T) {
+	in := []Candle{
+		{Open: 10, High: 12, Low: 9, Close: 11},
+		{Open: 11, High: 14, Low: 10, Close: 13},
+		{Open: 13, High: 13, Low: 8, Close: 9},
+	}
+	want := []Candle{
+		{Open: 10.5, High: 12, Low: 9, Close: 10.5},
+		{Open: 10.5, High: 14, Low: 10, Close: 12},
+		{Open: 11.25, High: 13, Low: 8, Close: 10.75},
+	}
+	got := HeikinAshiCandles(in)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("candle %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	b := NewHeikinAshiBars(NewTickBars(1))
+	for i, ev := range barTicks(10, 11, 12) {
+		c := b.Push(ev)
+		if len(c) != 1 {
+			t.Fatalf("tick %d: got %d bars", i, len(c))
+		}
+		if i == 2 && (c[0].Open != 10.5 || c[0].Close != 12) {
+			t.Errorf("bar %d = %+v", i, c[0])
+		}
+	}
+}
+
+func TestParseBars(t *testing.T) {
+	for _, spec := range []string{"time", "tick:100", "volume:2.5", "dollar:1e6", "renko:25", "ha:time", "ha:renko:5"} {
+		if _, err := ParseBars(spec, time.Minute); err != nil {
+			t.Errorf("ParseBars(%q): %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "1m", "tick:0", "tick:0.5", "tick:2.5", "renko:1e-12", "volume:x", "range:5", "ha:"} {
+		if _, err := ParseBars(spec, time.Minute); err == nil {
+			t.Errorf("ParseBars(%q) succeeded", spec)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package candle
 
 import (
+	"fmt"
 	"time"
 
 	"realtime-market-engine/internal/types"
@@ -8,6 +9,7 @@ import (
 
 type Candle struct {
 	Symbol    string    `json:"symbol"`
+	Source    string    `json:"source,omitempty"`
 	Start     time.Time `json:"start"`
 	End       time.Time `json:"end"`
 	Open      float64   `json:"open"`
@@ -15,65 +17,349 @@ type Candle struct {
 	Low       float64   `json:"low"`
 	Close     float64   `json:"close"`
 	Timestamp time.Time `json:"timestamp"`
+	Closed    bool      `json:"closed"`
+	Interval  string    `json:"interval,omitempty"`
+	// Revision counts the amendments of a closed candle by late ticks.
+	Revision int `json:"revision,omitempty"`
+
+	Volume         float64 `json:"volume"`
+	QuoteVolume    float64 `json:"quoteVolume"`
+	Trades         int64   `json:"trades"`
+	TakerBuyVolume float64 `json:"takerBuyVolume"`
 }
 
 type Aggregator struct {
 	interval time.Duration
+	empty    bool
+	lateness time.Duration
 
 	hasCurrent bool
 	current    Candle
+	openAt     time.Time // timestamp of the tick that set current.Open
+
+	// last is the most recently completed candle, used to fill gaps.
+	hasLast bool
+	last    Candle
+
+	// watermark is the latest tick or Advance time seen. Completed candles
+	// stay in recent, oldest first, until it passes their end by lateness.
+	watermark time.Time
+	recent    []recentCandle
+	dropped   int64
+}
+
+type recentCandle struct {
+	c      Candle
+	openAt time.Time
+}
+
+type AggregatorOption func(*Aggregator)
+
+// WithEmptyCandles makes Advance emit flat, zero-volume candles at the
+// previous close for intervals without ticks.
+func WithEmptyCandles() AggregatorOption {
+	return func(a *Aggregator) { a.empty = true }
+}
+
+// WithAllowedLateness keeps completed candles open to revision until the
+// watermark passes their end by d. A tick of such a candle amends it and Push
+// returns the revised candle with Revision incremented; a late tick of a
+// bucket that had no candle yields a new completed candle, older than the
+// ones before it. Ticks of buckets older than that are dropped and counted in
+// Dropped.
+func WithAllowedLateness(d time.Duration) AggregatorOption {
+	return func(a *Aggregator) { a.lateness = d }
 }
 
-func NewAggregator(interval time.Duration) *Aggregator {
+// maxGapCandles bounds how many empty candles one Advance emits; older
+// buckets of a longer gap are skipped.
+const maxGapCandles = 1000
+
+func NewAggregator(interval time.Duration, opts ...AggregatorOption) *Aggregator {
 	if interval <= 0 {
 		interval = 5 * time.Second
 	}
-	return &Aggregator{interval: interval}
+	a := &Aggregator{interval: interval}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
+// Push adds a tick and returns the candle it completed, if any. A tick of an
+// already completed bucket returns the revised candle instead (see
+// WithAllowedLateness) or is dropped.
 func (a *Aggregator) Push(ev types.PriceEvent) (Candle, bool) {
 	bucketStart := ev.Timestamp.Truncate(a.interval)
 	bucketEnd := bucketStart.Add(a.interval)
 
+	switch {
+	case a.hasCurrent && bucketStart.Before(a.current.Start),
+		!a.hasCurrent && a.hasLast && bucketStart.Before(a.last.End):
+		return a.late(ev, bucketStart)
+	}
+	if ev.Timestamp.After(a.watermark) {
+		a.watermark = ev.Timestamp
+	}
+
 	if !a.hasCurrent {
-		a.current = Candle{
-			Symbol:    ev.Symbol,
-			Start:     bucketStart,
-			End:       bucketEnd,
-			Open:      ev.Price,
-			High:      ev.Price,
-			Low:       ev.Price,
-			Close:     ev.Price,
-			Timestamp: ev.Timestamp,
-		}
-		a.hasCurrent = true
+		a.open(ev, bucketStart, bucketEnd)
 		return Candle{}, false
 	}
 
 	if a.current.Start.Equal(bucketStart) {
-		if ev.Price > a.current.High {
-			a.current.High = ev.Price
-		}
-		if ev.Price < a.current.Low {
-			a.current.Low = ev.Price
+		a.current.addAt(ev, &a.openAt)
+		return Candle{}, false
+	}
+
+	completed := a.complete()
+	a.open(ev, bucketStart, bucketEnd)
+	return completed, true
+}
+
+// Dropped returns the number of ticks that arrived too late to be applied.
+func (a *Aggregator) Dropped() int64 {
+	return a.dropped
+}
+
+func (a *Aggregator) open(ev types.PriceEvent, start, end time.Time) {
+	a.current = newCandle(ev, start, end)
+	a.openAt = ev.Timestamp
+	a.hasCurrent = true
+}
+
+// late applies a tick of a completed bucket to the retained candle of that
+// bucket, or starts a new candle with Revision 0 if the bucket had no ticks.
+func (a *Aggregator) late(ev types.PriceEvent, bucketStart time.Time) (Candle, bool) {
+	a.prune()
+	if a.lateness <= 0 || ev.Timestamp.Before(a.watermark.Add(-a.lateness)) {
+		a.dropped++
+		return Candle{}, false
+	}
+
+	i := 0
+	for i < len(a.recent) && a.recent[i].c.Start.Before(bucketStart) {
+		i++
+	}
+	if i == len(a.recent) || !a.recent[i].c.Start.Equal(bucketStart) {
+		// No candle was emitted for the bucket: this one is new, not a revision.
+		c := newCandle(ev, bucketStart, bucketStart.Add(a.interval))
+		c.Closed = true
+		a.recent = append(a.recent, recentCandle{})
+		copy(a.recent[i+1:], a.recent[i:])
+		a.recent[i] = recentCandle{c: c, openAt: ev.Timestamp}
+		return c, true
+	}
+	r := &a.recent[i]
+	if r.c.Trades == 0 && r.c.Volume == 0 {
+		// A filled gap candle: the tick is its first real trade.
+		r.c = newCandle(ev, r.c.Start, r.c.End)
+		r.c.Closed = true
+		r.openAt = ev.Timestamp
+	} else {
+		r.c.addAt(ev, &r.openAt)
+	}
+	r.c.Revision++
+	if a.hasLast && a.last.Start.Equal(r.c.Start) {
+		a.last = r.c
+	}
+	return r.c, true
+}
+
+// prune forgets completed candles that can no longer be revised.
+func (a *Aggregator) prune() {
+	limit := a.watermark.Add(-a.lateness)
+	n := 0
+	for n < len(a.recent) && !a.recent[n].c.End.After(limit) {
+		n++
+	}
+	a.recent = a.recent[n:]
+}
+
+// Advance completes the open candle once t, the wall clock or an event-time
+// watermark, reaches its end, so quiet markets do not delay candles until the
+// next tick. With WithEmptyCandles it also emits a flat candle for every
+// interval without ticks that ended by t. Calling Advance with a tick's
+// timestamp before Push yields the gap candles preceding that tick.
+func (a *Aggregator) Advance(t time.Time) []Candle {
+	if t.After(a.watermark) {
+		a.watermark = t
+	}
+	var out []Candle
+	if a.hasCurrent && !a.current.End.After(t) {
+		out = append(out, a.complete())
+	}
+	if !a.empty || !a.hasLast || a.hasCurrent {
+		return out
+	}
+
+	gap := int(t.Sub(a.last.End) / a.interval)
+	if gap > maxGapCandles {
+		a.last.End = a.last.End.Add(time.Duration(gap-maxGapCandles) * a.interval)
+	}
+	for !a.last.End.Add(a.interval).After(t) {
+		c := Candle{
+			Symbol:    a.last.Symbol,
+			Source:    a.last.Source,
+			Start:     a.last.End,
+			End:       a.last.End.Add(a.interval),
+			Open:      a.last.Close,
+			High:      a.last.Close,
+			Low:       a.last.Close,
+			Close:     a.last.Close,
+			Timestamp: a.last.End.Add(a.interval),
+			Closed:    true,
 		}
-		a.current.Close = ev.Price
-		a.current.Timestamp = ev.Timestamp
+		a.last = c
+		a.retain(c, c.Start)
+		out = append(out, c)
+	}
+	return out
+}
+
+// Flush completes the open candle before its end, e.g. on shutdown.
+func (a *Aggregator) Flush() (Candle, bool) {
+	if !a.hasCurrent {
 		return Candle{}, false
 	}
+	return a.complete(), true
+}
+
+func (a *Aggregator) complete() Candle {
+	c := a.current
+	c.Closed = true
+	a.hasCurrent = false
+	a.current = Candle{}
+	a.hasLast = true
+	a.last = c
+	a.retain(c, a.openAt)
+	return c
+}
 
-	completed := a.current
+func (a *Aggregator) retain(c Candle, openAt time.Time) {
+	if a.lateness <= 0 {
+		return
+	}
+	a.recent = append(a.recent, recentCandle{c: c, openAt: openAt})
+	a.prune()
+}
 
-	a.current = Candle{
+func newCandle(ev types.PriceEvent, start, end time.Time) Candle {
+	c := Candle{
 		Symbol:    ev.Symbol,
-		Start:     bucketStart,
-		End:       bucketEnd,
+		Source:    ev.Source,
+		Start:     start,
+		End:       end,
 		Open:      ev.Price,
 		High:      ev.Price,
 		Low:       ev.Price,
 		Close:     ev.Price,
 		Timestamp: ev.Timestamp,
 	}
+	c.addVolume(ev)
+	return c
+}
 
-	return completed, true
+// add extends the candle with a tick of its bucket.
+func (c *Candle) add(ev types.PriceEvent) {
+	if ev.Price > c.High {
+		c.High = ev.Price
+	}
+	if ev.Price < c.Low {
+		c.Low = ev.Price
+	}
+	c.Close = ev.Price
+	c.Timestamp = ev.Timestamp
+	c.addVolume(ev)
+}
+
+// addAt is add for ticks that may arrive out of order: Open and Close only
+// move for ticks before *openAt or at or after the last tick.
+func (c *Candle) addAt(ev types.PriceEvent, openAt *time.Time) {
+	open, close, ts := c.Open, c.Close, c.Timestamp
+	c.add(ev)
+	if ev.Timestamp.Before(ts) {
+		c.Close, c.Timestamp = close, ts
+	}
+	if ev.Timestamp.Before(*openAt) {
+		open = ev.Price
+		*openAt = ev.Timestamp
+	}
+	c.Open = open
+}
+
+// addVolume accumulates the traded size of ev. Taker-buy volume counts trades
+// where the buyer was the aggressor (buyer is not the maker).
+func (c *Candle) addVolume(ev types.PriceEvent) {
+	c.Volume += ev.Quantity
+	c.QuoteVolume += ev.Quantity * ev.Price
+	c.Trades += ev.TradeCount()
+	if !ev.BuyerIsMaker {
+		c.TakerBuyVolume += ev.Quantity
+	}
+}
+
+// AggregatorSnapshot is the serializable state of an Aggregator. OpenAt and
+// LastOpenAt are the timestamps of the ticks that set the candles' open.
+type AggregatorSnapshot struct {
+	Interval   time.Duration `json:"interval"`
+	Current    *Candle       `json:"current,omitempty"`
+	OpenAt     time.Time     `json:"openAt,omitempty"`
+	Last       *Candle       `json:"last,omitempty"`
+	LastOpenAt time.Time     `json:"lastOpenAt,omitempty"`
+}
+
+// Snapshot returns the open and the last completed candle, if any.
+func (a *Aggregator) Snapshot() AggregatorSnapshot {
+	s := AggregatorSnapshot{Interval: a.interval}
+	if a.hasCurrent {
+		c := a.current
+		s.Current, s.OpenAt = &c, a.openAt
+	}
+	if a.hasLast {
+		c := a.last
+		s.Last, s.LastOpenAt = &c, c.Start
+		for _, r := range a.recent {
+			if r.c.Start.Equal(c.Start) {
+				s.LastOpenAt = r.openAt
+			}
+		}
+	}
+	return s
+}
+
+// Restore replaces the open candle with the one in s. Snapshots taken with a
+// different interval are rejected. With lateness, the last completed candle
+// can be revised again; older ones are not in the snapshot.
+func (a *Aggregator) Restore(s AggregatorSnapshot) error {
+	if s.Interval != a.interval {
+		return fmt.Errorf("candle: snapshot interval %s does not match %s", s.Interval, a.interval)
+	}
+	a.hasCurrent = s.Current != nil
+	a.current = Candle{}
+	if s.Current != nil {
+		a.current = *s.Current
+	}
+	a.hasLast = s.Last != nil
+	a.last = Candle{}
+	if s.Last != nil {
+		a.last = *s.Last
+	}
+	a.openAt = s.OpenAt
+	if a.openAt.IsZero() {
+		a.openAt = a.current.Start
+	}
+	a.recent = nil
+	a.watermark = a.last.End
+	if a.current.Timestamp.After(a.watermark) {
+		a.watermark = a.current.Timestamp
+	}
+	if a.hasLast {
+		openAt := s.LastOpenAt
+		if openAt.IsZero() {
+			openAt = a.last.Start
+		}
+		a.retain(a.last, openAt)
+	}
+	return nil
 }
@@ -0,0 +1,174 @@
+package candle
+
+import (
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+func TestAggregatorVolume(t *testing.T) {
+	agg := NewAggregator(5 * time.Second)
+	t0 := time.Unix(1700000000, 0)
+
+	ticks := []types.PriceEvent{
+		{Symbol: "BTCUSDT", Price: 100, Quantity: 1, Timestamp: t0, FirstTradeID: 1, LastTradeID: 3},
+		{Symbol: "BTCUSDT", Price: 102, Quantity: 2, Timestamp: t0.Add(time.Second), BuyerIsMaker: true},
+		{Symbol: "BTCUSDT", Price: 101, Quantity: 0.5, Timestamp: t0.Add(2 * time.Second)},
+	}
+	for _, ev := range ticks {
+		if _, ok := agg.Push(ev); ok {
+			t.Fatalf("unexpected candle before bucket end")
+		}
+	}
+
+	c, ok := agg.Push(types.PriceEvent{Symbol: "BTCUSDT", Price: 99, Quantity: 1, Timestamp: t0.Add(5 * time.Second)})
+	if !ok {
+		t.Fatalf("expected completed candle")
+	}
+	if !c.Closed {
+		t.Errorf("completed candle should be closed")
+	}
+	if c.Open != 100 || c.High != 102 || c.Low != 100 || c.Close != 101 {
+		t.Errorf("unexpected OHLC: %+v", c)
+	}
+	if c.Volume != 3.5 {
+		t.Errorf("volume = %v, want 3.5", c.Volume)
+	}
+	if c.QuoteVolume != 100+204+50.5 {
+		t.Errorf("quote volume = %v, want 354.5", c.QuoteVolume)
+	}
+	if c.Trades != 5 {
+		t.Errorf("trades = %d, want 5", c.Trades)
+	}
+	if c.TakerBuyVolume != 1.5 {
+		t.Errorf("taker buy volume = %v, want 1.5", c.TakerBuyVolume)
+	}
+}
+
+func TestAggregatorAdvance(t *testing.T) {
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	tick := func(d time.Duration, price float64) types.PriceEvent {
+		return types.PriceEvent{Symbol: "BTCUSDT", Price: price, Quantity: 1, Timestamp: t0.Add(d)}
+	}
+
+	agg := NewAggregator(5 * time.Second)
+	agg.Push(tick(time.Second, 100))
+	if out := agg.Advance(t0.Add(4 * time.Second)); len(out) != 0 {
+		t.Fatalf("candle closed before its end: %+v", out)
+	}
+	out := agg.Advance(t0.Add(5 * time.Second))
+	if len(out) != 1 || !out[0].Closed || out[0].Close != 100 {
+		t.Fatalf("Advance at bucket end = %+v", out)
+	}
+	// Without empty candles a quiet period yields nothing.
+	if out := agg.Advance(t0.Add(time.Minute)); len(out) != 0 {
+		t.Fatalf("unexpected empty candles: %+v", out)
+	}
+	if _, ok := agg.Push(tick(time.Minute+time.Second, 101)); ok {
+		t.Fatalf("Push completed a candle already closed by Advance")
+	}
+
+	agg = NewAggregator(5*time.Second, WithEmptyCandles())
+	agg.Push(tick(time.Second, 100))
+	agg.Push(tick(2*time.Second, 102))
+	// A tick at 17s is the watermark for the candle and the two empty ones.
+	out = agg.Advance(t0.Add(17 * time.Second))
+	if len(out) != 3 {
+		t.Fatalf("got %d candles, want 3: %+v", len(out), out)
+	}
+	for i, c := range out[1:] {
+		wantStart := t0.Add(time.Duration(i+1) * 5 * time.Second)
+		if !c.Start.Equal(wantStart) || c.Open != 102 || c.High != 102 || c.Low != 102 || c.Close != 102 || c.Volume != 0 || !c.Closed {
+			t.Fatalf("empty candle %d = %+v", i, c)
+		}
+	}
+	agg.Push(tick(17*time.Second, 103))
+
+	c, ok := agg.Flush()
+	if !ok || !c.Closed || c.Close != 103 || !c.Start.Equal(t0.Add(15*time.Second)) {
+		t.Fatalf("Flush = %+v, %v", c, ok)
+	}
+	if _, ok := agg.Flush(); ok {
+		t.Fatalf("second Flush returned a candle")
+	}
+}
+
+func TestAggregatorLateTicks(t *testing.T) {
+	agg := NewAggregator(5*time.Second, WithAllowedLateness(3*time.Second))
+	t0 := time.Unix(1700000000, 0)
+	tick := func(sec float64, price float64) types.PriceEvent {
+		return types.PriceEvent{Symbol: "BTCUSDT", Price: price, Quantity: 1, Timestamp: t0.Add(time.Duration(sec * float64(time.Second)))}
+	}
+
+	agg.Push(tick(1, 100))
+	agg.Push(tick(3, 102))
+	// Out of order within the open bucket: extends Open back, keeps Close.
+	agg.Push(tick(0.5, 99))
+	c, ok := agg.Push(tick(6, 105))
+	if !ok {
+		t.Fatalf("expected completed candle")
+	}
+	if c.Open != 99 || c.Close != 102 || c.Low != 99 || c.Revision != 0 {
+		t.Fatalf("completed candle = %+v", c)
+	}
+
+	// Within the lateness window: amends the closed candle.
+	c, ok = agg.Push(tick(4, 110))
+	if !ok {
+		t.Fatalf("expected revised candle")
+	}
+	if !c.Start.Equal(t0) || c.Revision != 1 || c.High != 110 || c.Close != 110 || c.Volume != 4 {
+		t.Errorf("revised candle = %+v", c)
+	}
+
+	// Past the window: dropped.
+	agg.Push(tick(9, 106))
+	if _, ok := agg.Push(tick(4.5, 90)); ok {
+		t.Errorf("tick beyond allowed lateness should be dropped")
+	}
+	if agg.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", agg.Dropped())
+	}
+
+	// The open candle is unaffected by late ticks.
+	c, _ = agg.Push(tick(10, 107))
+	if c.Open != 105 || c.Close != 106 || c.High != 106 || c.Volume != 2 {
+		t.Errorf("second candle = %+v", c)
+	}
+}
+
+func TestAggregatorLateTickOfEmptyBucket(t *testing.T) {
+	agg := NewAggregator(5*time.Second, WithAllowedLateness(10*time.Second))
+	t0 := time.Unix(1700000000, 0)
+	agg.Push(types.PriceEvent{Price: 100, Quantity: 1, Timestamp: t0})
+	agg.Push(types.PriceEvent{Price: 101, Quantity: 1, Timestamp: t0.Add(11 * time.Second)})
+
+	// The 5s bucket had no ticks: its first tick yields a new candle.
+	c, ok := agg.Push(types.PriceEvent{Price: 90, Quantity: 1, Timestamp: t0.Add(6 * time.Second)})
+	if !ok || !c.Start.Equal(t0.Add(5*time.Second)) || c.Revision != 0 || c.Close != 90 || !c.Closed {
+		t.Fatalf("late candle = %+v, %v", c, ok)
+	}
+	// Its next tick is a revision.
+	c, ok = agg.Push(types.PriceEvent{Price: 95, Quantity: 1, Timestamp: t0.Add(7 * time.Second)})
+	if !ok || c.Revision != 1 || c.Close != 95 || c.Volume != 2 {
+		t.Fatalf("revised candle = %+v, %v", c, ok)
+	}
+}
+
+func TestAggregatorLateTicksDroppedByDefault(t *testing.T) {
+	agg := NewAggregator(5 * time.Second)
+	t0 := time.Unix(1700000000, 0)
+	agg.Push(types.PriceEvent{Price: 100, Timestamp: t0})
+	agg.Push(types.PriceEvent{Price: 101, Timestamp: t0.Add(5 * time.Second)})
+	if _, ok := agg.Push(types.PriceEvent{Price: 50, Timestamp: t0.Add(time.Second)}); ok {
+		t.Fatalf("late tick should not complete or revise a candle")
+	}
+	c, _ := agg.Flush()
+	if !c.Start.Equal(t0.Add(5*time.Second)) || c.Low != 101 {
+		t.Errorf("open candle = %+v", c)
+	}
+	if agg.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", agg.Dropped())
+	}
+}
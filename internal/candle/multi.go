@@ -0,0 +1,231 @@
+package candle
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+// DefaultTimeframes are the intervals built by the engine unless configured otherwise.
+var DefaultTimeframes = []time.Duration{
+	time.Second,
+	5 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	4 * time.Hour,
+	24 * time.Hour,
+}
+
+// MultiAggregator builds candles of several intervals from one tick stream.
+// Only the smallest interval is built from ticks; every other interval is
+// rolled up from the largest smaller interval that divides it (1d from 4h,
+// 15m from 5m, ...). Buckets are aligned to UTC boundaries.
+//
+// A candle closes as soon as a tick at or past its end arrives, so a higher
+// timeframe closes together with its last lower-timeframe candle.
+//
+// Ticks older than the open base candle are dropped; revisions of closed
+// candles are left to Aggregator.
+type MultiAggregator struct {
+	levels  []*level
+	closed  time.Time // end of the last closed base candle
+	dropped int64
+}
+
+type level struct {
+	interval time.Duration
+	label    string
+	parents  []int // indexes of the levels rolled up from this one
+	has      bool
+	current  Candle
+}
+
+func NewMultiAggregator(intervals ...time.Duration) (*MultiAggregator, error) {
+	if len(intervals) == 0 {
+		return nil, fmt.Errorf("candle: no intervals")
+	}
+	ivs := append([]time.Duration(nil), intervals...)
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i] < ivs[j] })
+
+	m := &MultiAggregator{}
+	for i, iv := range ivs {
+		if iv <= 0 {
+			return nil, fmt.Errorf("candle: invalid interval %s", iv)
+		}
+		if i > 0 && iv == ivs[i-1] {
+			return nil, fmt.Errorf("candle: duplicate interval %s", iv)
+		}
+		if iv%ivs[0] != 0 {
+			return nil, fmt.Errorf("candle: interval %s is not a multiple of %s", iv, ivs[0])
+		}
+		m.levels = append(m.levels, &level{interval: iv, label: FormatInterval(iv)})
+	}
+
+	// Each interval is rolled up from the largest smaller interval dividing it.
+	for j := 1; j < len(m.levels); j++ {
+		for i := j - 1; i >= 0; i-- {
+			if m.levels[j].interval%m.levels[i].interval == 0 {
+				m.levels[i].parents = append(m.levels[i].parents, j)
+				break
+			}
+		}
+	}
+	return m, nil
+}
+
+// Intervals returns the configured intervals in ascending order.
+func (m *MultiAggregator) Intervals() []time.Duration {
+	out := make([]time.Duration, len(m.levels))
+	for i, l := range m.levels {
+		out[i] = l.interval
+	}
+	return out
+}
+
+// Push adds a tick and returns the candles it completed, smaller intervals
+// first. Completed candles have Closed and Interval set.
+func (m *MultiAggregator) Push(ev types.PriceEvent) []Candle {
+	base := m.levels[0]
+	if ev.Timestamp.Before(m.closed) || base.has && ev.Timestamp.Before(base.current.Start) {
+		m.dropped++
+		return nil
+	}
+	out := m.advance(ev.Timestamp, nil)
+
+	if !base.has {
+		start := ev.Timestamp.Truncate(base.interval)
+		base.current = newCandle(ev, start, start.Add(base.interval))
+		base.current.Interval = base.label
+		base.has = true
+	} else {
+		base.current.add(ev)
+	}
+	return out
+}
+
+// Dropped returns the number of ticks that arrived after their candle closed.
+func (m *MultiAggregator) Dropped() int64 {
+	return m.dropped
+}
+
+// Advance completes every open candle that ends at or before t, the wall
+// clock or an event-time watermark, without waiting for the next tick.
+func (m *MultiAggregator) Advance(t time.Time) []Candle {
+	return m.advance(t, nil)
+}
+
+// Flush completes all open candles before their end, e.g. on shutdown.
+func (m *MultiAggregator) Flush() []Candle {
+	var out []Candle
+	for i, l := range m.levels {
+		if l.has {
+			out = m.close(i, out)
+		}
+	}
+	return out
+}
+
+func (m *MultiAggregator) advance(t time.Time, out []Candle) []Candle {
+	for i, l := range m.levels {
+		if l.has && !l.current.End.After(t) {
+			out = m.close(i, out)
+		}
+	}
+	return out
+}
+
+func (m *MultiAggregator) close(i int, out []Candle) []Candle {
+	l := m.levels[i]
+	c := l.current
+	c.Closed = true
+	if i == 0 {
+		m.closed = c.End
+	}
+	l.has = false
+	l.current = Candle{}
+	out = append(out, c)
+
+	for _, pi := range l.parents {
+		out = m.rollUp(pi, c, out)
+	}
+	return out
+}
+
+func (m *MultiAggregator) rollUp(i int, c Candle, out []Candle) []Candle {
+	p := m.levels[i]
+	start := c.Start.Truncate(p.interval)
+	if p.has && !p.current.Start.Equal(start) {
+		out = m.close(i, out)
+	}
+	if !p.has {
+		p.current = c
+		p.current.Start = start
+		p.current.End = start.Add(p.interval)
+		p.current.Closed = false
+		p.current.Interval = p.label
+		p.has = true
+		return out
+	}
+	p.current.merge(c)
+	return out
+}
+
+// merge extends the candle with a later, smaller candle of the same bucket.
+func (c *Candle) merge(o Candle) {
+	if o.High > c.High {
+		c.High = o.High
+	}
+	if o.Low < c.Low {
+		c.Low = o.Low
+	}
+	c.Close = o.Close
+	c.Timestamp = o.Timestamp
+	c.Volume += o.Volume
+	c.QuoteVolume += o.QuoteVolume
+	c.Trades += o.Trades
+	c.TakerBuyVolume += o.TakerBuyVolume
+}
+
+// MultiSnapshot is the serializable state of a MultiAggregator: the open
+// candle of each interval, keyed by its label.
+type MultiSnapshot struct {
+	Open map[string]Candle `json:"open"`
+}
+
+func (m *MultiAggregator) Snapshot() MultiSnapshot {
+	s := MultiSnapshot{Open: make(map[string]Candle)}
+	for _, l := range m.levels {
+		if l.has {
+			s.Open[l.label] = l.current
+		}
+	}
+	return s
+}
+
+// Restore loads the open candles in s; intervals that are not configured are ignored.
+func (m *MultiAggregator) Restore(s MultiSnapshot) {
+	for _, l := range m.levels {
+		c, ok := s.Open[l.label]
+		l.has, l.current = ok, c
+	}
+}
+
+// FormatInterval renders d in Binance interval notation, e.g. 5s, 15m, 4h or 1d.
+func FormatInterval(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	default:
+		return d.String()
+	}
+}
@@ -0,0 +1,91 @@
+package candle
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+func TestMultiAggregatorMatchesSingle(t *testing.T) {
+	m, err := NewMultiAggregator(time.Hour, time.Second, 5*time.Second, time.Minute, 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	single := NewAggregator(15 * time.Minute)
+
+	t0 := time.Date(2026, 2, 8, 9, 58, 0, 0, time.UTC)
+	got := make(map[string][]Candle)
+	var want []Candle
+	for i := 0; i < 3*3600; i += 3 {
+		ev := types.PriceEvent{
+			Symbol:    "BTCUSDT",
+			Price:     100 + 5*math.Sin(float64(i)/500),
+			Quantity:  float64(i%7) + 1,
+			Timestamp: t0.Add(time.Duration(i)*time.Second + 250*time.Millisecond),
+		}
+		for _, c := range m.Push(ev) {
+			got[c.Interval] = append(got[c.Interval], c)
+		}
+		if c, ok := single.Push(ev); ok {
+			want = append(want, c)
+		}
+	}
+
+	// 09:58-12:58 closes the partial 09:00 hour and the full 10:00 and 11:00 hours.
+	if n := len(got["1h"]); n != 3 {
+		t.Fatalf("got %d 1h candles, want 3", n)
+	}
+	if s := got["1h"][1].Start; !s.Equal(time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("first full 1h candle starts at %v", s)
+	}
+
+	// The first 15m bucket is incomplete in both, the rest must agree.
+	rolled := got["15m"]
+	if len(rolled) != len(want) {
+		t.Fatalf("got %d 15m candles, want %d", len(rolled), len(want))
+	}
+	for i := range want {
+		w, g := want[i], rolled[i]
+		if !g.Start.Equal(w.Start) || !g.End.Equal(w.End) || g.Open != w.Open || g.High != w.High ||
+			g.Low != w.Low || g.Close != w.Close || math.Abs(g.Volume-w.Volume) > 1e-9 || g.Trades != w.Trades {
+			t.Fatalf("15m candle %d:\n got %+v\nwant %+v", i, g, w)
+		}
+		if !g.Closed || g.Interval != "15m" {
+			t.Fatalf("15m candle %d not tagged: %+v", i, g)
+		}
+	}
+}
+
+func TestMultiAggregatorClosesOnBoundary(t *testing.T) {
+	m, err := NewMultiAggregator(DefaultTimeframes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2026, 2, 8, 10, 0, 59, 500e6, time.UTC)
+	if out := m.Push(types.PriceEvent{Price: 100, Timestamp: t0}); len(out) != 0 {
+		t.Fatalf("unexpected candles: %+v", out)
+	}
+
+	out := m.Push(types.PriceEvent{Price: 101, Timestamp: t0.Add(600 * time.Millisecond)})
+	var labels []string
+	for _, c := range out {
+		labels = append(labels, c.Interval)
+	}
+	if len(labels) != 3 || labels[0] != "1s" || labels[1] != "5s" || labels[2] != "1m" {
+		t.Fatalf("closed intervals = %v, want [1s 5s 1m]", labels)
+	}
+}
+
+func TestNewMultiAggregatorErrors(t *testing.T) {
+	for _, ivs := range [][]time.Duration{
+		nil,
+		{time.Second, time.Second},
+		{2 * time.Second, 3 * time.Second},
+	} {
+		if _, err := NewMultiAggregator(ivs...); err == nil {
+			t.Errorf("NewMultiAggregator(%v) succeeded", ivs)
+		}
+	}
+}
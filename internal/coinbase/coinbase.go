@@ -0,0 +1,213 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/market"
+	"realtime-market-engine/internal/types"
+	"realtime-market-engine/internal/wsstream"
+
+	"github.com/gorilla/websocket"
+)
+
+const wsURL = "wss://advanced-trade-ws.coinbase.com"
+
+// Coinbase lists USD books where Binance lists USDT ones; canonical symbols
+// keep the Binance quote so the same instrument lines up across venues.
+var toVenueQuote = map[string]string{"USDT": "USD"}
+
+// ProductID converts a canonical symbol (BTCUSDT) to a Coinbase product ID (BTC-USD).
+func ProductID(symbol string) (string, error) {
+	inst, err := market.ParseSymbol(symbol)
+	if err != nil {
+		return "", err
+	}
+	quote := inst.Quote
+	if q, ok := toVenueQuote[quote]; ok {
+		quote = q
+	}
+	return inst.Base + "-" + quote, nil
+}
+
+// Symbol converts a Coinbase product ID (BTC-USD) to a canonical symbol (BTCUSDT).
+func Symbol(productID string) (string, error) {
+	base, quote, ok := strings.Cut(strings.ToUpper(productID), "-")
+	if !ok || base == "" || quote == "" {
+		return "", fmt.Errorf("invalid product id %q", productID)
+	}
+	for canonical, venue := range toVenueQuote {
+		if quote == venue {
+			quote = canonical
+			break
+		}
+	}
+	return base + quote, nil
+}
+
+type Option func(*Source)
+
+// WithURL overrides the WebSocket URL.
+func WithURL(u string) Option {
+	return func(s *Source) { s.url = u }
+}
+
+// WithDialer sets the dialer used for WebSocket connections.
+func WithDialer(d *websocket.Dialer) Option {
+	return func(s *Source) {
+		if d != nil {
+			s.dialer = d
+		}
+	}
+}
+
+// Source streams public trades from the Coinbase Advanced Trade
+// market_trades channel.
+type Source struct {
+	url    string
+	dialer *websocket.Dialer
+}
+
+func NewSource(opts ...Option) *Source {
+	s := &Source{url: wsURL, dialer: websocket.DefaultDialer}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Source) Name() string {
+	return "coinbase"
+}
+
+type subscribeMessage struct {
+	Type       string   `json:"type"`
+	ProductIDs []string `json:"product_ids"`
+	Channel    string   `json:"channel"`
+}
+
+type tradesMessage struct {
+	Channel string `json:"channel"`
+	Events  []struct {
+		Type   string `json:"type"`
+		Trades []struct {
+			TradeID   string    `json:"trade_id"`
+			ProductID string    `json:"product_id"`
+			Price     string    `json:"price"`
+			Size      string    `json:"size"`
+			Side      string    `json:"side"`
+			Time      time.Time `json:"time"`
+		} `json:"trades"`
+	} `json:"events"`
+}
+
+func (s *Source) Trades(ctx context.Context, symbols []string) (<-chan types.PriceEvent, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("at least one symbol required")
+	}
+
+	canonical := make(map[string]string, len(symbols))
+	products := make([]string, 0, len(symbols))
+	for _, sym := range symbols {
+		p, err := ProductID(sym)
+		if err != nil {
+			return nil, err
+		}
+		canonical[p] = strings.ToUpper(sym)
+		products = append(products, p)
+	}
+
+	ch := make(chan types.PriceEvent, 100*len(symbols))
+
+	subscribe := func(conn *websocket.Conn) error {
+		return conn.WriteJSON(subscribeMessage{Type: "subscribe", ProductIDs: products, Channel: "market_trades"})
+	}
+
+	go func() {
+		defer close(ch)
+
+		wsstream.Run(ctx, s.dialer, s.url, "coinbase market_trades: "+strings.Join(products, ","), subscribe, func(message []byte) bool {
+			evs, err := parseTrades(message, canonical)
+			if err != nil {
+				log.Printf("coinbase trade parse error: %v", err)
+			}
+			for _, ev := range evs {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		})
+	}()
+
+	return ch, nil
+}
+
+// parseTrades decodes the trades of a market_trades update in the order they
+// happened, skipping the snapshot, products not in canonical and trades that
+// fail to parse; the latter are reported in the error.
+func parseTrades(message []byte, canonical map[string]string) ([]types.PriceEvent, error) {
+	var msg tradesMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Channel != "market_trades" {
+		return nil, nil
+	}
+
+	var out []types.PriceEvent
+	var errs []error
+	for _, e := range msg.Events {
+		// The snapshot replays recent history on every (re)connect.
+		if e.Type != "update" {
+			continue
+		}
+		// Updates list the newest trade first.
+		for i := len(e.Trades) - 1; i >= 0; i-- {
+			t := e.Trades[i]
+			sym, ok := canonical[t.ProductID]
+			if !ok {
+				continue
+			}
+			price, err := strconv.ParseFloat(t.Price, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("trade %s price: %w", t.TradeID, err))
+				continue
+			}
+			size, err := strconv.ParseFloat(t.Size, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("trade %s size: %w", t.TradeID, err))
+				continue
+			}
+			id, _ := strconv.ParseInt(t.TradeID, 10, 64)
+
+			out = append(out, types.PriceEvent{
+				Symbol:       sym,
+				Price:        price,
+				Quantity:     size,
+				Timestamp:    t.Time,
+				Source:       "coinbase",
+				AggTradeID:   id,
+				FirstTradeID: id,
+				LastTradeID:  id,
+				// side is the taker side, so a selling taker hit a resting bid.
+				BuyerIsMaker: t.Side == "SELL",
+			})
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// Candles aggregates trades locally; the venue's candle channel only offers 5m bars.
+func (s *Source) Candles(ctx context.Context, symbol, interval string) (<-chan candle.Candle, error) {
+	return market.CandlesFromTrades(ctx, s, symbol, interval)
+}
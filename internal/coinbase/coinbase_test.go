@@ -0,0 +1,77 @@
+package coinbase
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProductMapping(t *testing.T) {
+	cases := []struct {
+		symbol  string
+		product string
+	}{
+		{"BTCUSDT", "BTC-USD"},
+		{"ETHBTC", "ETH-BTC"},
+		{"SOLEUR", "SOL-EUR"},
+		{"USDCEUR", "USDC-EUR"},
+	}
+	for _, tc := range cases {
+		p, err := ProductID(tc.symbol)
+		if err != nil || p != tc.product {
+			t.Errorf("ProductID(%q) = %q, %v; want %q", tc.symbol, p, err, tc.product)
+		}
+		s, err := Symbol(tc.product)
+		if err != nil || s != tc.symbol {
+			t.Errorf("Symbol(%q) = %q, %v; want %q", tc.product, s, err, tc.symbol)
+		}
+	}
+}
+
+func TestParseTrades(t *testing.T) {
+	canonical := map[string]string{"BTC-USD": "BTCUSDT"}
+
+	snapshot := []byte(`{"channel":"market_trades","events":[{"type":"snapshot","trades":[{"trade_id":"1","product_id":"BTC-USD","price":"60000","size":"1","side":"BUY","time":"2026-02-08T10:00:00Z"}]}]}`)
+	if evs, err := parseTrades(snapshot, canonical); err != nil || len(evs) != 0 {
+		t.Fatalf("snapshot: got %v, %v; want no trades", evs, err)
+	}
+
+	update := []byte(`{"channel":"market_trades","events":[{"type":"update","trades":[
+		{"trade_id":"12","product_id":"BTC-USD","price":"60001.5","size":"0.25","side":"BUY","time":"2026-02-08T10:00:02Z"},
+		{"trade_id":"99","product_id":"ETH-USD","price":"3000","size":"1","side":"BUY","time":"2026-02-08T10:00:01Z"},
+		{"trade_id":"11","product_id":"BTC-USD","price":"60000","size":"0.5","side":"SELL","time":"2026-02-08T10:00:01Z"}
+	]}]}`)
+	evs, err := parseTrades(update, canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("got %d trades, want 2 (ETH-USD is not subscribed)", len(evs))
+	}
+	// Oldest first.
+	first, second := evs[0], evs[1]
+	if first.AggTradeID != 11 || second.AggTradeID != 12 {
+		t.Fatalf("trade ids %d, %d; want 11, 12", first.AggTradeID, second.AggTradeID)
+	}
+	if first.Symbol != "BTCUSDT" || first.Source != "coinbase" || first.Price != 60000 || first.Quantity != 0.5 || !first.BuyerIsMaker {
+		t.Errorf("unexpected trade: %+v", first)
+	}
+	if want := time.Date(2026, 2, 8, 10, 0, 1, 0, time.UTC); !first.Timestamp.Equal(want) {
+		t.Errorf("timestamp = %v, want %v", first.Timestamp, want)
+	}
+	if second.BuyerIsMaker {
+		t.Errorf("buying taker reported as maker")
+	}
+
+	bad := []byte(`{"channel":"market_trades","events":[{"type":"update","trades":[
+		{"trade_id":"14","product_id":"BTC-USD","price":"oops","size":"1","side":"BUY","time":"2026-02-08T10:00:04Z"},
+		{"trade_id":"13","product_id":"BTC-USD","price":"60002","size":"1","side":"BUY","time":"2026-02-08T10:00:03Z"}
+	]}]}`)
+	evs, err = parseTrades(bad, canonical)
+	if err == nil || len(evs) != 1 || evs[0].AggTradeID != 13 {
+		t.Errorf("got %v, %v; want trade 13 and an error", evs, err)
+	}
+
+	if evs, err := parseTrades([]byte(`{"channel":"heartbeats"}`), canonical); err != nil || evs != nil {
+		t.Errorf("heartbeat: got %v, %v", evs, err)
+	}
+}
@@ -0,0 +1,135 @@
+package detector
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"realtime-market-engine/internal/alert"
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/trend"
+)
+
+func init() {
+	Register("trend", newTrend)
+	Register("breakout", newBreakout)
+}
+
+// Trend runs a trend.EMACrossoverDetector on ticks. Params: mode (tick or
+// time), fast and slow (tick mode), fast-half-life and slow-half-life (time
+// mode), confirm, min-diff and cooldown.
+type Trend struct {
+	d *trend.EMACrossoverDetector
+}
+
+func newTrend(p Params) (Detector, error) {
+	if err := p.Check("mode", "fast", "slow", "fast-half-life", "slow-half-life", "confirm", "min-diff", "cooldown"); err != nil {
+		return nil, err
+	}
+	mode, err1 := trend.ParseEMAMode(p.String("mode", string(trend.EMAModeTick)))
+	fast, err2 := p.Int("fast", 20)
+	slow, err3 := p.Int("slow", 50)
+	fastHalfLife, err4 := p.Duration("fast-half-life", 30*time.Second)
+	slowHalfLife, err5 := p.Duration("slow-half-life", 2*time.Minute)
+	confirm, err6 := p.Int("confirm", 3)
+	minDiff, err7 := p.Float("min-diff", 0.00005)
+	cooldown, err8 := p.Duration("cooldown", 10*time.Second)
+	if err := errors.Join(err1, err2, err3, err4, err5, err6, err7, err8); err != nil {
+		return nil, err
+	}
+	if mode == trend.EMAModeTime {
+		return &Trend{d: trend.NewTimeEMACrossoverDetector(fastHalfLife, slowHalfLife, confirm, minDiff, cooldown)}, nil
+	}
+	return &Trend{d: trend.NewEMACrossoverDetector(fast, slow, confirm, minDiff, cooldown)}, nil
+}
+
+func (t *Trend) Push(in Input) (Signal, bool) {
+	if in.Tick == nil {
+		return Signal{}, false
+	}
+	change, ok := t.d.Push(*in.Tick)
+	if !ok {
+		return Signal{}, false
+	}
+	return Signal{
+		Detector:  "trend",
+		Symbol:    change.Symbol,
+		Source:    change.Source,
+		Direction: string(change.Trend),
+		Timestamp: change.Timestamp,
+		Event:     change,
+	}, true
+}
+
+func (t *Trend) Snapshot() (json.RawMessage, error) {
+	return json.Marshal(t.d.Snapshot())
+}
+
+func (t *Trend) Restore(b json.RawMessage) error {
+	var s trend.EMACrossoverSnapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return t.d.Restore(s)
+}
+
+// Breakout runs an alert.BreakoutDetector on closed candles. Params:
+// lookback, pct, cooldown, mode (pct, atr or stddev) and k, the volatility
+// multiple of the atr and stddev modes.
+type Breakout struct {
+	d *alert.BreakoutDetector
+}
+
+func newBreakout(p Params) (Detector, error) {
+	if err := p.Check("lookback", "pct", "cooldown", "mode", "k"); err != nil {
+		return nil, err
+	}
+	lookback, err1 := p.Duration("lookback", 5*time.Minute)
+	pct, err2 := p.Float("pct", 0.001)
+	cooldown, err3 := p.Duration("cooldown", 30*time.Second)
+	mode, err4 := alert.ParseThresholdMode(p.String("mode", string(alert.ThresholdPct)))
+	k, err5 := p.Float("k", 2)
+	if err := errors.Join(err1, err2, err3, err4, err5); err != nil {
+		return nil, err
+	}
+	var opts []alert.BreakoutOption
+	if mode != alert.ThresholdPct {
+		opts = append(opts, alert.WithVolatilityThreshold(mode, k))
+	}
+	return &Breakout{d: alert.NewBreakoutDetector(lookback, pct, cooldown, opts...)}, nil
+}
+
+func (b *Breakout) Push(in Input) (Signal, bool) {
+	if in.Candle == nil {
+		return Signal{}, false
+	}
+	bo, ok := b.d.Push(*in.Candle)
+	if !ok {
+		return Signal{}, false
+	}
+	return Signal{
+		Detector:  "breakout",
+		Symbol:    bo.Symbol,
+		Source:    bo.Source,
+		Direction: string(bo.Dir),
+		Timestamp: bo.Timestamp,
+		Event:     bo,
+	}, true
+}
+
+func (b *Breakout) Revise(c candle.Candle) {
+	b.d.Revise(c)
+}
+
+func (b *Breakout) Snapshot() (json.RawMessage, error) {
+	return json.Marshal(b.d.Snapshot())
+}
+
+func (b *Breakout) Restore(raw json.RawMessage) error {
+	var s alert.BreakoutSnapshot
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return err
+	}
+	b.d.Restore(s)
+	return nil
+}
@@ -0,0 +1,205 @@
+// Package detector defines the interface shared by the signal detectors and
+// a registry that builds them by name, so the engine can run any set of
+// detectors from configuration.
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
+)
+
+// Input is either a tick or a closed candle.
+type Input struct {
+	Tick   *types.PriceEvent
+	Candle *candle.Candle
+}
+
+func TickInput(ev types.PriceEvent) Input { return Input{Tick: &ev} }
+
+func CandleInput(c candle.Candle) Input { return Input{Candle: &c} }
+
+// Signal is the common envelope of detector output. Event is the detector's
+// own event type and is what gets published on /ws.
+type Signal struct {
+	Detector  string
+	Symbol    string
+	Source    string
+	Direction string
+	Timestamp time.Time
+	Event     any
+}
+
+// Detector consumes ticks and candles; detectors ignore the input kind they
+// do not use.
+type Detector interface {
+	Push(in Input) (Signal, bool)
+}
+
+// Stateful detectors can be snapshotted across restarts.
+type Stateful interface {
+	Snapshot() (json.RawMessage, error)
+	Restore(json.RawMessage) error
+}
+
+// Reviser detectors accept amended versions of candles they already saw.
+type Reviser interface {
+	Revise(c candle.Candle)
+}
+
+// Params are the settings of a detector, e.g. lookback=5m.
+type Params map[string]string
+
+// String returns the param key, or def if it is not set.
+func (p Params) String(key, def string) string {
+	if v, ok := p[key]; ok {
+		return v
+	}
+	return def
+}
+
+// Int returns the integer param key, or def if it is not set.
+func (p Params) Int(key string, def int) (int, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return n, nil
+}
+
+// Float returns the float param key, or def if it is not set.
+func (p Params) Float(key string, def float64) (float64, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	x, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return x, nil
+}
+
+// Duration returns the duration param key, or def if it is not set.
+func (p Params) Duration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return d, nil
+}
+
+// Check fails on the first param, in key order, that is not among keys, so a
+// misspelled param is not silently ignored.
+func (p Params) Check(keys ...string) error {
+	var unknown []string
+	for k := range p {
+		if !slices.Contains(keys, k) {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown param %q (have %s)", unknown[0], strings.Join(keys, ", "))
+}
+
+// Factory builds a detector from its params.
+type Factory func(Params) (Detector, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a detector available to New under name. It panics if the
+// name is taken, like http.Handle.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic("detector: " + name + " registered twice")
+	}
+	factories[name] = f
+}
+
+// New builds the detector registered as name.
+func New(name string, p Params) (Detector, error) {
+	mu.RLock()
+	f, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("detector: unknown detector %q (have %s)", name, strings.Join(Names(), ", "))
+	}
+	d, err := f(p)
+	if err != nil {
+		return nil, fmt.Errorf("detector %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// Names returns the registered detector names, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]string, 0, len(factories))
+	for name := range factories {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Spec names a detector and its params.
+type Spec struct {
+	Name   string
+	Params Params
+}
+
+// ParseSpecs parses a semicolon separated list of detectors with optional
+// params, e.g. "trend;breakout:lookback=10m,pct=0.002".
+func ParseSpecs(s string) ([]Spec, error) {
+	var out []Spec
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, args, _ := strings.Cut(part, ":")
+		spec := Spec{Name: strings.TrimSpace(name), Params: Params{}}
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("detector %s listed twice", spec.Name)
+		}
+		seen[spec.Name] = true
+		for _, kv := range strings.Split(args, ",") {
+			if strings.TrimSpace(kv) == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return nil, fmt.Errorf("detector %s: invalid param %q, want key=value", spec.Name, kv)
+			}
+			spec.Params[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		out = append(out, spec)
+	}
+	return out, nil
+}
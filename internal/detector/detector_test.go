@@ -0,0 +1,64 @@
+package detector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
+)
+
+func TestParseSpecs(t *testing.T) {
+	got, err := ParseSpecs("trend; breakout:lookback=10m, pct=0.002 ;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Spec{
+		{Name: "trend", Params: Params{}},
+		{Name: "breakout", Params: Params{"lookback": "10m", "pct": "0.002"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	for _, s := range []string{"trend;trend", "breakout:lookback"} {
+		if _, err := ParseSpecs(s); err == nil {
+			t.Errorf("ParseSpecs(%q) succeeded", s)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("nope", nil); err == nil {
+		t.Error("unknown detector built")
+	}
+	if _, err := New("breakout", Params{"lookback": "soon"}); err == nil {
+		t.Error("invalid param accepted")
+	}
+	for name, p := range map[string]Params{"trend": {"fats": "10"}, "breakout": {"lookback": "10m", "pcnt": "0.002"}} {
+		if _, err := New(name, p); err == nil || !strings.Contains(err.Error(), "unknown param") {
+			t.Errorf("%s with %v: err = %v, want unknown param", name, p, err)
+		}
+	}
+
+	d, err := New("breakout", Params{"lookback": "10s", "pct": "0.01", "cooldown": "0s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Push(TickInput(types.PriceEvent{Price: 1})); ok {
+		t.Error("breakout fired on a tick")
+	}
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	for i, price := range []float64{100, 100.5, 99.8, 102} {
+		c := candle.Candle{Symbol: "BTCUSDT", Start: t0.Add(time.Duration(i) * time.Second), End: t0.Add(time.Duration(i+1) * time.Second), High: price, Low: price, Close: price}
+		sig, ok := d.Push(CandleInput(c))
+		if ok != (i == 3) {
+			t.Fatalf("candle %d: signal %v", i, ok)
+		}
+		if ok && (sig.Detector != "breakout" || sig.Direction != "up" || sig.Event == nil) {
+			t.Errorf("signal = %+v", sig)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/alert"
+	"realtime-market-engine/internal/binance"
+	"realtime-market-engine/internal/binance/binancetest"
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/httpapi"
+	"realtime-market-engine/internal/store"
+	"realtime-market-engine/internal/trend"
+	"realtime-market-engine/internal/types"
+)
+
+// TestBinanceOffline runs the engine end to end against the fake Binance:
+// warm-up from REST klines, then live trades from the aggTrade stream.
+func TestBinanceOffline(t *testing.T) {
+	srv := binancetest.NewServer()
+	defer srv.Close()
+
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	var klines []candle.Candle
+	for i := 0; i < 60; i++ {
+		open := start.Add(time.Duration(i) * time.Second)
+		klines = append(klines, candle.Candle{
+			Symbol: "BTCUSDT", Start: open, End: open.Add(time.Second - time.Millisecond),
+			Open: 100, High: 100.05, Low: 99.95, Close: 100, Volume: 1,
+		})
+	}
+	srv.AddKlines("BTCUSDT", "1s", klines)
+
+	// Flat trades after the warm-up, then a jump above the lookback high.
+	live := start.Add(time.Minute)
+	var trades []types.PriceEvent
+	var steps []binancetest.Step
+	for i := 0; i < 30; i++ {
+		price := 100.0
+		if i >= 20 {
+			price = 101
+		}
+		ev := types.PriceEvent{
+			Symbol: "BTCUSDT", Price: price, Quantity: 0.1,
+			Timestamp:  live.Add(time.Duration(i) * 300 * time.Millisecond),
+			AggTradeID: int64(i + 1), FirstTradeID: int64(i + 1), LastTradeID: int64(i + 1),
+		}
+		trades = append(trades, ev)
+		steps = append(steps, binancetest.AggTrade(ev))
+	}
+	srv.AddAggTrades(trades...)
+	srv.AddSession(steps...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	st := store.NewPriceStore(store.WithRetention(store.Retention{Ticks: 100, Candles: 100}))
+	e := New(testConfig(), st, httpapi.NewHub())
+
+	fetched, err := binance.NewKlineFetcher(binance.WithRESTBaseURL(srv.URL())).FetchKlines(ctx, "BTCUSDT", "1s", start, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Warmup("binance", "BTCUSDT", fetched)
+
+	src := binance.NewSource(binance.WithWSBaseURL(srv.WSURL()), binance.WithRESTBaseURL(srv.URL()))
+	ch, err := src.Trades(ctx, []string{"BTCUSDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for ev := range ch {
+		e.HandleTrade(ev)
+		if n++; n == len(trades) {
+			cancel()
+		}
+	}
+	if n != len(trades) {
+		t.Fatalf("handled %d trades, want %d", n, len(trades))
+	}
+	e.Flush()
+
+	if ev, ok := st.Get("binance", "BTCUSDT"); !ok || ev.Price != 101 || !ev.Timestamp.Equal(trades[len(trades)-1].Timestamp) {
+		t.Fatalf("last price = %+v", ev)
+	}
+	// 59 warm-up candles (the last kline's bucket stays open), the 10:01:00
+	// candle that completes it and the 9 live ones.
+	if got := len(st.LastCandles("binance", "BTCUSDT", 100)); got != 69 {
+		t.Fatalf("got %d candles, want 69", got)
+	}
+
+	snap := e.Snapshot()
+	if len(snap.Pipelines) != 1 {
+		t.Fatalf("got %d pipelines, want 1", len(snap.Pipelines))
+	}
+	var tr trend.EMACrossoverSnapshot
+	var bo alert.BreakoutSnapshot
+	if err := json.Unmarshal(snap.Pipelines[0].Detectors["trend"], &tr); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(snap.Pipelines[0].Detectors["breakout"], &bo); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.LastTimestamp.Equal(trades[len(trades)-1].Timestamp) {
+		t.Errorf("trend last timestamp = %s", tr.LastTimestamp)
+	}
+	if !bo.LastSignalAt.After(live) {
+		t.Errorf("no breakout on the live jump, last signal at %s", bo.LastSignalAt)
+	}
+}
@@ -0,0 +1,465 @@
+package engine
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"realtime-market-engine/internal/alert"
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/detector"
+	"realtime-market-engine/internal/httpapi"
+	"realtime-market-engine/internal/market"
+	"realtime-market-engine/internal/store"
+	"realtime-market-engine/internal/trend"
+	"realtime-market-engine/internal/types"
+)
+
+// Config holds the detector and aggregation settings shared by every pipeline.
+type Config struct {
+	EMAMode          trend.EMAMode
+	EMAFast          int
+	EMASlow          int
+	EMAFastHalfLife  time.Duration
+	EMASlowHalfLife  time.Duration
+	TrendConfirm     int
+	TrendMinDiff     float64
+	TrendCooldown    time.Duration
+	CandleInterval   time.Duration
+	CloseDelay       time.Duration
+	AllowedLateness  time.Duration
+	EmptyCandles     bool
+	BreakoutLookback time.Duration
+	BreakoutPct      float64
+	BreakoutCooldown time.Duration
+	BreakoutMode     alert.ThresholdMode
+	BreakoutK        float64
+
+	// Timeframes are the candle intervals published on /ws; empty disables them.
+	Timeframes []time.Duration
+
+	// Detectors run on every pipeline, in order; nil runs trend and breakout.
+	// The trend and breakout settings above are their default params.
+	Detectors []detector.Spec
+
+	// Klines disables trade aggregation; candles are fed via HandleKline instead.
+	Klines bool
+}
+
+// RegisterFlags binds the pipeline flags to fs so that the live engine and
+// the replay command accept the same settings.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	c.EMAMode = trend.EMAModeTick
+	fs.Func("ema-mode", "Trend EMA mode: tick (windows in ticks) or time (half-lives) (default tick)", func(s string) error {
+		m, err := trend.ParseEMAMode(s)
+		c.EMAMode = m
+		return err
+	})
+	fs.IntVar(&c.EMAFast, "ema-fast", 20, "Fast EMA window (ticks)")
+	fs.IntVar(&c.EMASlow, "ema-slow", 50, "Slow EMA window (ticks)")
+	fs.DurationVar(&c.EMAFastHalfLife, "ema-fast-half-life", 30*time.Second, "Fast EMA half-life with -ema-mode=time")
+	fs.DurationVar(&c.EMASlowHalfLife, "ema-slow-half-life", 2*time.Minute, "Slow EMA half-life with -ema-mode=time")
+	fs.IntVar(&c.TrendConfirm, "trend-confirm", 3, "Confirm trend flip after N consecutive ticks")
+	fs.Float64Var(&c.TrendMinDiff, "trend-min-diff", 0.00005, "Minimum relative EMA separation (abs(fast-slow)/price) required to confirm a trend flip")
+	fs.DurationVar(&c.TrendCooldown, "trend-cooldown", 10*time.Second, "Minimum time between trend flip notifications")
+	fs.DurationVar(&c.CandleInterval, "candle-interval", 5*time.Second, "Candle aggregation interval")
+	fs.DurationVar(&c.CloseDelay, "candle-close-delay", 250*time.Millisecond, "Close candles this long after their end by the wall clock when no tick arrives")
+	fs.DurationVar(&c.AllowedLateness, "allowed-lateness", 2*time.Second, "Amend closed candles with ticks at most this far behind the newest tick; older ticks are dropped")
+	fs.BoolVar(&c.EmptyCandles, "empty-candles", false, "Emit flat zero-volume candles for intervals without trades")
+	fs.DurationVar(&c.BreakoutLookback, "breakout-lookback", 5*time.Minute, "Breakout lookback window (uses completed candles)")
+	fs.Float64Var(&c.BreakoutPct, "breakout-pct", 0.001, "Breakout threshold as a fraction (0.001 = 0.1%)")
+	fs.DurationVar(&c.BreakoutCooldown, "breakout-cooldown", 30*time.Second, "Minimum time between breakout notifications")
+	c.BreakoutMode = alert.ThresholdPct
+	fs.Func("breakout-mode", "Breakout threshold: pct (-breakout-pct), atr or stddev (-breakout-k times the ATR or return standard deviation of the lookback window) (default pct)", func(s string) error {
+		m, err := alert.ParseThresholdMode(s)
+		c.BreakoutMode = m
+		return err
+	})
+	fs.Float64Var(&c.BreakoutK, "breakout-k", 2, "Volatility multiple of the breakout threshold with -breakout-mode=atr or stddev")
+
+	c.Timeframes = candle.DefaultTimeframes
+	fs.Func("timeframes", "Comma separated candle intervals published on /ws (default 1s,5s,1m,5m,15m,1h,4h,1d; empty disables)", func(s string) error {
+		tfs, err := parseTimeframes(s)
+		if err != nil {
+			return err
+		}
+		if len(tfs) > 0 {
+			if _, err := candle.NewMultiAggregator(tfs...); err != nil {
+				return err
+			}
+		}
+		c.Timeframes = tfs
+		return nil
+	})
+	fs.Func("detectors", "Semicolon separated detectors with optional params, e.g. 'trend;breakout:lookback=10m,pct=0.002' (default trend;breakout; available: "+strings.Join(detector.Names(), ", ")+")", func(s string) error {
+		specs, err := detector.ParseSpecs(s)
+		if err != nil {
+			return err
+		}
+		for _, spec := range specs {
+			if _, err := detector.New(spec.Name, spec.Params); err != nil {
+				return err
+			}
+		}
+		c.Detectors = specs
+		return nil
+	})
+}
+
+var defaultDetectors = []detector.Spec{{Name: "trend"}, {Name: "breakout"}}
+
+// params returns the params of spec on top of the defaults from the flags.
+func (c *Config) params(spec detector.Spec) detector.Params {
+	p := detector.Params{}
+	switch spec.Name {
+	case "trend":
+		if c.EMAMode != "" {
+			p["mode"] = string(c.EMAMode)
+		}
+		p["fast"] = strconv.Itoa(c.EMAFast)
+		p["slow"] = strconv.Itoa(c.EMASlow)
+		p["fast-half-life"] = c.EMAFastHalfLife.String()
+		p["slow-half-life"] = c.EMASlowHalfLife.String()
+		p["confirm"] = strconv.Itoa(c.TrendConfirm)
+		p["min-diff"] = strconv.FormatFloat(c.TrendMinDiff, 'g', -1, 64)
+		p["cooldown"] = c.TrendCooldown.String()
+	case "breakout":
+		p["lookback"] = c.BreakoutLookback.String()
+		p["pct"] = strconv.FormatFloat(c.BreakoutPct, 'g', -1, 64)
+		p["cooldown"] = c.BreakoutCooldown.String()
+		if c.BreakoutMode != "" {
+			p["mode"] = string(c.BreakoutMode)
+			p["k"] = strconv.FormatFloat(c.BreakoutK, 'g', -1, 64)
+		}
+	}
+	for k, v := range spec.Params {
+		p[k] = v
+	}
+	return p
+}
+
+func parseTimeframes(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := market.ParseInterval(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// Engine routes trades and klines to one pipeline per venue and symbol,
+// creating pipelines on first use. It is not safe for concurrent use; the
+// callers run it from a single dispatch goroutine.
+type Engine struct {
+	cfg       Config
+	st        *store.PriceStore
+	hub       *httpapi.Hub
+	pipelines map[string]*pipeline
+	warmUntil map[string]time.Time
+
+	// counters are read by Metrics from other goroutines.
+	mu       sync.Mutex
+	counters map[string]*counters
+}
+
+type counters struct {
+	lateDropped      atomic.Int64
+	timeframeDropped atomic.Int64
+	revised          atomic.Int64
+}
+
+func New(cfg Config, st *store.PriceStore, hub *httpapi.Hub) *Engine {
+	return &Engine{
+		cfg:       cfg,
+		st:        st,
+		hub:       hub,
+		pipelines: make(map[string]*pipeline),
+		warmUntil: make(map[string]time.Time),
+		counters:  make(map[string]*counters),
+	}
+}
+
+// PipelineMetrics counts the late ticks of one venue and symbol.
+type PipelineMetrics struct {
+	Source string `json:"source"`
+	Symbol string `json:"symbol"`
+	// LateTicksDropped counts ticks behind the allowed lateness of the candle aggregator.
+	LateTicksDropped int64 `json:"lateTicksDropped"`
+	// TimeframeTicksDropped counts ticks of already closed multi-timeframe candles.
+	TimeframeTicksDropped int64 `json:"timeframeTicksDropped"`
+	// CandlesRevised counts closed candles amended by late ticks.
+	CandlesRevised int64 `json:"candlesRevised"`
+}
+
+// Metrics returns the counters of every pipeline. Unlike the other methods
+// it is safe to call from any goroutine.
+func (e *Engine) Metrics() []PipelineMetrics {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]PipelineMetrics, 0, len(e.counters))
+	for key, c := range e.counters {
+		source, symbol, _ := strings.Cut(key, "|")
+		out = append(out, PipelineMetrics{
+			Source:                source,
+			Symbol:                symbol,
+			LateTicksDropped:      c.lateDropped.Load(),
+			TimeframeTicksDropped: c.timeframeDropped.Load(),
+			CandlesRevised:        c.revised.Load(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Source != out[j].Source {
+			return out[i].Source < out[j].Source
+		}
+		return out[i].Symbol < out[j].Symbol
+	})
+	return out
+}
+
+// HandleTrade runs ev through the store, hub, aggregator and detectors.
+// Trades already covered by Warmup are dropped.
+func (e *Engine) HandleTrade(ev types.PriceEvent) {
+	if until, ok := e.warmUntil[ev.Source+"|"+ev.Symbol]; ok && !ev.Timestamp.After(until) {
+		return
+	}
+	e.pipeline(ev.Source, ev.Symbol).handle(ev, e.st, e.hub)
+}
+
+// Warmup seeds the pipeline of source and symbol from historical klines
+// before live trades are handled. Each closed kline becomes one synthetic
+// trade at its close price and End time carrying its volume, so the
+// aggregator and trend detector see one tick per kline rather than the
+// trades behind it; in kline mode the kline itself also goes to the breakout
+// detector. Completed candles are kept in the store, nothing is published.
+func (e *Engine) Warmup(source, symbol string, klines []candle.Candle) {
+	p := e.pipeline(source, symbol)
+	for _, c := range klines {
+		if !c.Closed {
+			continue
+		}
+		ev := types.PriceEvent{
+			Symbol:    symbol,
+			Source:    source,
+			Price:     c.Close,
+			Quantity:  c.Volume,
+			Timestamp: c.End,
+		}
+		p.handle(ev, e.st, nil)
+		if p.agg == nil {
+			c.Source = source
+			p.handleCandle(c, e.st, nil)
+		}
+		e.warmUntil[source+"|"+symbol] = c.End
+	}
+}
+
+// HandleKline feeds an exchange-native candle; only closed candles not
+// already covered by Warmup are used.
+func (e *Engine) HandleKline(c candle.Candle) {
+	if !c.Closed {
+		return
+	}
+	if until, ok := e.warmUntil[c.Source+"|"+c.Symbol]; ok && !c.End.After(until) {
+		return
+	}
+	e.pipeline(c.Source, c.Symbol).handleCandle(c, e.st, e.hub)
+}
+
+// Advance closes the candles that ended more than CloseDelay before now.
+// The engine calls it periodically so quiet markets still complete candles.
+func (e *Engine) Advance(now time.Time) {
+	t := now.Add(-e.cfg.CloseDelay)
+	for _, p := range e.pipelines {
+		p.advance(t, e.st, e.hub)
+	}
+}
+
+// Flush completes all open candles, e.g. on shutdown.
+func (e *Engine) Flush() {
+	for _, p := range e.pipelines {
+		if p.agg != nil {
+			if c, ok := p.agg.Flush(); ok {
+				p.handleCandle(c, e.st, e.hub)
+			}
+		}
+		if p.tf != nil {
+			for _, c := range p.tf.Flush() {
+				e.hub.PublishCandle(c)
+			}
+		}
+	}
+}
+
+// Reset drops all pipeline state, as if no event had been seen.
+func (e *Engine) Reset() {
+	clear(e.pipelines)
+	clear(e.warmUntil)
+	e.mu.Lock()
+	clear(e.counters)
+	e.mu.Unlock()
+}
+
+func (e *Engine) pipeline(source, symbol string) *pipeline {
+	key := source + "|" + symbol
+	p, ok := e.pipelines[key]
+	if !ok {
+		p = e.newPipeline(key)
+		e.pipelines[key] = p
+	}
+	return p
+}
+
+func (e *Engine) newPipeline(key string) *pipeline {
+	e.mu.Lock()
+	c, ok := e.counters[key]
+	if !ok {
+		c = &counters{}
+		e.counters[key] = c
+	}
+	e.mu.Unlock()
+
+	p := &pipeline{counters: c}
+	specs := e.cfg.Detectors
+	if specs == nil {
+		specs = defaultDetectors
+	}
+	for _, spec := range specs {
+		d, err := detector.New(spec.Name, e.cfg.params(spec))
+		if err != nil {
+			// Validated by RegisterFlags; other callers lose the detector.
+			log.Printf("pipeline %s: %v", key, err)
+			continue
+		}
+		p.detectors = append(p.detectors, namedDetector{name: spec.Name, d: d})
+	}
+	if !e.cfg.Klines {
+		var opts []candle.AggregatorOption
+		if e.cfg.EmptyCandles {
+			opts = append(opts, candle.WithEmptyCandles())
+		}
+		if e.cfg.AllowedLateness > 0 {
+			opts = append(opts, candle.WithAllowedLateness(e.cfg.AllowedLateness))
+		}
+		p.agg = candle.NewAggregator(e.cfg.CandleInterval, opts...)
+	}
+	if len(e.cfg.Timeframes) > 0 {
+		// Validated by RegisterFlags; other callers get no timeframes on error.
+		p.tf, _ = candle.NewMultiAggregator(e.cfg.Timeframes...)
+	}
+	return p
+}
+
+// pipeline holds the per-symbol stateful components of the engine.
+// When agg is nil, candles come from the exchange kline stream via HandleKline.
+// A nil hub marks warm-up: nothing is published or logged and the synthetic
+// ticks stay out of the store.
+type pipeline struct {
+	agg       *candle.Aggregator
+	tf        *candle.MultiAggregator
+	detectors []namedDetector
+	counters  *counters
+}
+
+type namedDetector struct {
+	name string
+	d    detector.Detector
+}
+
+func (p *pipeline) handle(ev types.PriceEvent, st *store.PriceStore, hub *httpapi.Hub) {
+	if hub != nil {
+		st.Update(ev)
+		hub.PublishPrice(ev)
+	}
+
+	if p.agg != nil {
+		// The tick is the watermark for candles (and gaps) before it.
+		for _, c := range p.agg.Advance(ev.Timestamp) {
+			p.handleCandle(c, st, hub)
+		}
+		dropped := p.agg.Dropped()
+		c, ok := p.agg.Push(ev)
+		switch {
+		case ok && c.Revision > 0:
+			p.reviseCandle(c, st, hub)
+		case ok:
+			p.handleCandle(c, st, hub)
+		case p.agg.Dropped() != dropped:
+			p.counters.lateDropped.Add(1)
+		}
+	}
+
+	if p.tf != nil {
+		dropped := p.tf.Dropped()
+		for _, c := range p.tf.Push(ev) {
+			if hub != nil {
+				hub.PublishCandle(c)
+			}
+		}
+		if p.tf.Dropped() != dropped {
+			p.counters.timeframeDropped.Add(1)
+		}
+	}
+
+	p.detect(detector.TickInput(ev), hub)
+}
+
+// detect runs every detector on in and publishes their signals.
+func (p *pipeline) detect(in detector.Input, hub *httpapi.Hub) {
+	for _, nd := range p.detectors {
+		sig, ok := nd.d.Push(in)
+		if !ok || hub == nil {
+			continue
+		}
+		b, err := json.Marshal(sig.Event)
+		if err == nil {
+			hub.PublishJSON(b)
+		}
+		log.Printf("%s: %s %s %s", sig.Detector, sig.Source, sig.Symbol, sig.Direction)
+	}
+}
+
+func (p *pipeline) advance(t time.Time, st *store.PriceStore, hub *httpapi.Hub) {
+	if p.agg != nil {
+		for _, c := range p.agg.Advance(t) {
+			p.handleCandle(c, st, hub)
+		}
+	}
+	if p.tf != nil {
+		for _, c := range p.tf.Advance(t) {
+			hub.PublishCandle(c)
+		}
+	}
+}
+
+func (p *pipeline) handleCandle(c candle.Candle, st *store.PriceStore, hub *httpapi.Hub) {
+	st.AddCandle(c)
+	p.detect(detector.CandleInput(c), hub)
+}
+
+// reviseCandle replaces a candle amended by late ticks. Detectors holding
+// candles see the revision, but signals are not re-evaluated.
+func (p *pipeline) reviseCandle(c candle.Candle, st *store.PriceStore, hub *httpapi.Hub) {
+	st.ReviseCandle(c)
+	for _, nd := range p.detectors {
+		if r, ok := nd.d.(detector.Reviser); ok {
+			r.Revise(c)
+		}
+	}
+	p.counters.revised.Add(1)
+	if hub != nil {
+		hub.PublishCandleRevision(c)
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/alert"
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/detector"
+	"realtime-market-engine/internal/httpapi"
+	"realtime-market-engine/internal/store"
+	"realtime-market-engine/internal/trend"
+	"realtime-market-engine/internal/types"
+)
+
+func TestWarmup(t *testing.T) {
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	st := store.NewPriceStore(store.WithRetention(store.Retention{Ticks: 100, Candles: 100}))
+	e := New(testConfig(), st, httpapi.NewHub())
+
+	var klines []candle.Candle
+	for i := 0; i < 60; i++ {
+		open := start.Add(time.Duration(i) * time.Second)
+		klines = append(klines, candle.Candle{
+			Symbol: "BTCUSDT", Start: open, End: open.Add(time.Second - time.Millisecond),
+			Open: 100, High: 101, Low: 99, Close: 100 + float64(i)/10, Volume: 1, Closed: true,
+		})
+	}
+	klines = append(klines, candle.Candle{Symbol: "BTCUSDT", Start: start.Add(time.Minute), End: start.Add(time.Minute + 999*time.Millisecond), Close: 500})
+	e.Warmup("binance", "BTCUSDT", klines)
+
+	snap := e.Snapshot()
+	if len(snap.Pipelines) != 1 {
+		t.Fatalf("got %d pipelines, want 1", len(snap.Pipelines))
+	}
+	var tr trend.EMACrossoverSnapshot
+	var bo alert.BreakoutSnapshot
+	if err := json.Unmarshal(snap.Pipelines[0].Detectors["trend"], &tr); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(snap.Pipelines[0].Detectors["breakout"], &bo); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.HasEMA || tr.LastTimestamp != klines[59].End {
+		t.Fatalf("trend not seeded up to the last closed kline: %+v", tr)
+	}
+	if len(bo.Candles) == 0 {
+		t.Fatal("breakout window empty after warm-up")
+	}
+	if _, ok := st.Get("binance", "BTCUSDT"); ok {
+		t.Fatal("warm-up ticks leaked into the store")
+	}
+	if n := len(st.LastCandles("binance", "BTCUSDT", 100)); n == 0 {
+		t.Fatal("warm-up candles not kept in the store")
+	}
+
+	// Live trades already covered by the warm-up are dropped.
+	e.HandleTrade(types.PriceEvent{Symbol: "BTCUSDT", Source: "binance", Price: 1, Timestamp: start.Add(30 * time.Second)})
+	if _, ok := st.Get("binance", "BTCUSDT"); ok {
+		t.Fatal("stale live trade was handled")
+	}
+	e.HandleTrade(types.PriceEvent{Symbol: "BTCUSDT", Source: "binance", Price: 106, Timestamp: start.Add(time.Minute)})
+	if ev, ok := st.Get("binance", "BTCUSDT"); !ok || ev.Price != 106 {
+		t.Fatalf("live trade not handled: %+v", ev)
+	}
+}
+
+func TestLateTicks(t *testing.T) {
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	st := store.NewPriceStore(store.WithRetention(store.Retention{Candles: 100}))
+	cfg := testConfig()
+	cfg.AllowedLateness = 2 * time.Second
+	e := New(cfg, st, httpapi.NewHub())
+
+	trade := func(ms int, price float64) {
+		e.HandleTrade(types.PriceEvent{Symbol: "BTCUSDT", Source: "binance", Price: price, Quantity: 1, Timestamp: start.Add(time.Duration(ms) * time.Millisecond)})
+	}
+	trade(100, 100)
+	trade(1100, 101)
+	trade(1200, 102)
+	trade(900, 120)  // amends the closed 10:00:00 candle
+	trade(3500, 103) // moves the watermark past its lateness
+	trade(4100, 104) // closes the 10:00:03 candle
+	trade(2500, 90)  // first tick of the 10:00:02 bucket: a new candle
+	trade(800, 80)   // dropped
+
+	candles := st.LastCandles("binance", "BTCUSDT", 10)
+	if len(candles) != 4 || candles[0].High != 120 || candles[0].Revision != 1 {
+		t.Fatalf("store candles = %+v", candles)
+	}
+	if c := candles[2]; !c.Start.Equal(start.Add(2*time.Second)) || c.Close != 90 || c.Revision != 0 {
+		t.Fatalf("late new candle = %+v", c)
+	}
+	var bo alert.BreakoutSnapshot
+	if err := json.Unmarshal(e.Snapshot().Pipelines[0].Detectors["breakout"], &bo); err != nil {
+		t.Fatal(err)
+	}
+	if len(bo.Candles) != 4 || !bo.Candles[2].Start.Equal(start.Add(2*time.Second)) {
+		t.Fatalf("breakout window = %+v", bo.Candles)
+	}
+	m := e.Metrics()
+	if len(m) != 1 || m[0].CandlesRevised != 1 || m[0].LateTicksDropped != 1 {
+		t.Fatalf("metrics = %+v", m)
+	}
+}
+
+// countingDetector signals on every candle.
+type countingDetector struct{ n int }
+
+func (d *countingDetector) Push(in detector.Input) (detector.Signal, bool) {
+	if in.Candle == nil {
+		return detector.Signal{}, false
+	}
+	d.n++
+	return detector.Signal{Detector: "counting", Symbol: in.Candle.Symbol, Event: map[string]int{"n": d.n}}, true
+}
+
+// countingBuilt collects the countingDetectors built since the last reset;
+// the registry is global, so the factory is registered once per process.
+var countingBuilt []*countingDetector
+
+func init() {
+	detector.Register("counting", func(p detector.Params) (detector.Detector, error) {
+		d := &countingDetector{}
+		countingBuilt = append(countingBuilt, d)
+		return d, nil
+	})
+}
+
+func TestCustomDetector(t *testing.T) {
+	countingBuilt = nil
+
+	cfg := testConfig()
+	cfg.Detectors = []detector.Spec{{Name: "counting"}}
+	e := New(cfg, store.NewPriceStore(), httpapi.NewHub())
+	for _, ev := range ticks(time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC), 0, 40) {
+		e.HandleTrade(ev)
+	}
+	built := countingBuilt
+	if len(built) != 2 {
+		t.Fatalf("built %d detectors, want one per pipeline", len(built))
+	}
+	if built[0].n != 11 {
+		t.Fatalf("detector saw %d candles, want 11", built[0].n)
+	}
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/detector"
+)
+
+// ErrSnapshotStale is returned by LoadSnapshot for snapshots older than the max age.
+var ErrSnapshotStale = errors.New("engine: snapshot too old")
+
+// Version 2 keeps detector state by detector name.
+const snapshotVersion = 2
+
+// Snapshot is the serializable state of all pipelines.
+type Snapshot struct {
+	Version   int                `json:"version"`
+	SavedAt   time.Time          `json:"savedAt"`
+	Pipelines []PipelineSnapshot `json:"pipelines"`
+}
+
+type PipelineSnapshot struct {
+	Source     string                     `json:"source"`
+	Symbol     string                     `json:"symbol"`
+	Aggregator *candle.AggregatorSnapshot `json:"aggregator,omitempty"`
+	Timeframes *candle.MultiSnapshot      `json:"timeframes,omitempty"`
+	Detectors  map[string]json.RawMessage `json:"detectors,omitempty"`
+}
+
+// Snapshot captures the detector and aggregator state of every pipeline.
+func (e *Engine) Snapshot() Snapshot {
+	s := Snapshot{Version: snapshotVersion, SavedAt: time.Now().UTC()}
+	for key, p := range e.pipelines {
+		source, symbol, _ := strings.Cut(key, "|")
+		ps := PipelineSnapshot{Source: source, Symbol: symbol, Detectors: make(map[string]json.RawMessage)}
+		for _, nd := range p.detectors {
+			sd, ok := nd.d.(detector.Stateful)
+			if !ok {
+				continue
+			}
+			b, err := sd.Snapshot()
+			if err != nil {
+				log.Printf("snapshot %s %s %s: %v", source, symbol, nd.name, err)
+				continue
+			}
+			ps.Detectors[nd.name] = b
+		}
+		if p.agg != nil {
+			as := p.agg.Snapshot()
+			ps.Aggregator = &as
+		}
+		if p.tf != nil {
+			ts := p.tf.Snapshot()
+			ps.Timeframes = &ts
+		}
+		s.Pipelines = append(s.Pipelines, ps)
+	}
+	sort.Slice(s.Pipelines, func(i, j int) bool {
+		a, b := s.Pipelines[i], s.Pipelines[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Symbol < b.Symbol
+	})
+	return s
+}
+
+// Restore loads the pipelines in s. Pipelines whose settings no longer match
+// (e.g. a different EMA window) are left fresh and reported in the error.
+// Detectors missing from the snapshot start fresh.
+func (e *Engine) Restore(s Snapshot) error {
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("engine: unsupported snapshot version %d", s.Version)
+	}
+
+	var errs []error
+	for _, ps := range s.Pipelines {
+		key := ps.Source + "|" + ps.Symbol
+		p := e.newPipeline(key)
+		if err := p.restoreDetectors(ps.Detectors); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", ps.Source, ps.Symbol, err))
+			continue
+		}
+		if p.agg != nil && ps.Aggregator != nil {
+			if err := p.agg.Restore(*ps.Aggregator); err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", ps.Source, ps.Symbol, err))
+				continue
+			}
+		}
+		if p.tf != nil && ps.Timeframes != nil {
+			p.tf.Restore(*ps.Timeframes)
+		}
+		e.pipelines[key] = p
+	}
+	return errors.Join(errs...)
+}
+
+func (p *pipeline) restoreDetectors(states map[string]json.RawMessage) error {
+	for _, nd := range p.detectors {
+		b, ok := states[nd.name]
+		sd, stateful := nd.d.(detector.Stateful)
+		if !ok || !stateful {
+			continue
+		}
+		if err := sd.Restore(b); err != nil {
+			return fmt.Errorf("%s: %w", nd.name, err)
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot writes s to path atomically via a temp file and rename.
+func SaveSnapshot(path string, s Snapshot) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot. Snapshots saved more
+// than maxAge ago are rejected with ErrSnapshotStale; zero disables the check.
+func LoadSnapshot(path string, maxAge time.Duration) (Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return Snapshot{}, fmt.Errorf("engine: corrupt snapshot %s: %w", path, err)
+	}
+	if age := time.Since(s.SavedAt); maxAge > 0 && age > maxAge {
+		return Snapshot{}, fmt.Errorf("%w: saved %s ago", ErrSnapshotStale, age.Round(time.Second))
+	}
+	return s, nil
+}
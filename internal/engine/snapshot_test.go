@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/httpapi"
+	"realtime-market-engine/internal/store"
+	"realtime-market-engine/internal/types"
+)
+
+func testConfig() Config {
+	return Config{
+		EMAFast:          5,
+		EMASlow:          12,
+		TrendConfirm:     2,
+		TrendCooldown:    time.Second,
+		CandleInterval:   time.Second,
+		BreakoutLookback: 10 * time.Second,
+		BreakoutPct:      0.0005,
+		BreakoutCooldown: 2 * time.Second,
+	}
+}
+
+func ticks(start time.Time, from, to int) []types.PriceEvent {
+	var out []types.PriceEvent
+	for i := from; i < to; i++ {
+		for _, sym := range []string{"BTCUSDT", "ETHUSDT"} {
+			out = append(out, types.PriceEvent{
+				Symbol:    sym,
+				Source:    "binance",
+				Price:     100 + 3*math.Sin(float64(i)/15),
+				Quantity:  0.1,
+				Timestamp: start.Add(time.Duration(i) * 300 * time.Millisecond),
+			})
+		}
+	}
+	return out
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	hub := httpapi.NewHub()
+
+	live := New(testConfig(), store.NewPriceStore(), hub)
+	for _, ev := range ticks(start, 0, 200) {
+		live.HandleTrade(ev)
+	}
+
+	b, err := json.Marshal(live.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Pipelines) != 2 {
+		t.Fatalf("snapshot has %d pipelines, want 2", len(snap.Pipelines))
+	}
+
+	restarted := New(testConfig(), store.NewPriceStore(), hub)
+	if err := restarted.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both engines must now evolve identically.
+	for _, ev := range ticks(start, 200, 400) {
+		live.HandleTrade(ev)
+		restarted.HandleTrade(ev)
+	}
+	want, got := live.Snapshot(), restarted.Snapshot()
+	want.SavedAt, got.SavedAt = time.Time{}, time.Time{}
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	if string(gotJSON) != string(wantJSON) {
+		t.Fatalf("restored engine diverged:\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+}
+
+func TestRestoreLateTick(t *testing.T) {
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	cfg := testConfig()
+	cfg.AllowedLateness = 2 * time.Second
+	hub := httpapi.NewHub()
+
+	live := New(cfg, store.NewPriceStore(), hub)
+	for _, ev := range ticks(start, 0, 10) {
+		live.HandleTrade(ev)
+	}
+	restartedStore := store.NewPriceStore(store.WithRetention(store.Retention{Candles: 10}))
+	restarted := New(cfg, restartedStore, hub)
+	if err := restarted.Restore(live.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A late tick of the last completed candle, 10:00:01-10:00:02, revises
+	// it after a restart just as it does live.
+	late := types.PriceEvent{Symbol: "BTCUSDT", Source: "binance", Price: 120, Quantity: 0.1, Timestamp: start.Add(1950 * time.Millisecond)}
+	live.HandleTrade(late)
+	restarted.HandleTrade(late)
+
+	want, got := live.Snapshot(), restarted.Snapshot()
+	want.SavedAt, got.SavedAt = time.Time{}, time.Time{}
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	if string(gotJSON) != string(wantJSON) {
+		t.Fatalf("restored engine diverged:\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+	last := got.Pipelines[0].Aggregator.Last
+	if last == nil || !last.Start.Equal(start.Add(time.Second)) || last.Revision != 1 || last.High != 120 {
+		t.Fatalf("last candle = %+v, want the revised 10:00:01 candle", last)
+	}
+	if cs := restartedStore.LastCandles("binance", "BTCUSDT", 10); len(cs) != 0 {
+		t.Fatalf("late tick produced new candles %+v", cs)
+	}
+}
+
+func TestRestoreMismatch(t *testing.T) {
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	hub := httpapi.NewHub()
+
+	e := New(testConfig(), store.NewPriceStore(), hub)
+	for _, ev := range ticks(start, 0, 50) {
+		e.HandleTrade(ev)
+	}
+	snap := e.Snapshot()
+
+	cfg := testConfig()
+	cfg.EMASlow = 30
+	other := New(cfg, store.NewPriceStore(), hub)
+	if err := other.Restore(snap); err == nil {
+		t.Fatal("Restore accepted a snapshot with different EMA windows")
+	}
+	if n := len(other.Snapshot().Pipelines); n != 0 {
+		t.Fatalf("mismatched pipelines were restored: %d", n)
+	}
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "engine.json")
+	s := Snapshot{Version: snapshotVersion, SavedAt: time.Now().Add(-time.Hour)}
+	if err := SaveSnapshot(path, s); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadSnapshot(path, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != s.Version || !got.SavedAt.Equal(s.SavedAt) {
+		t.Fatalf("LoadSnapshot = %+v", got)
+	}
+
+	if _, err := LoadSnapshot(path, 30*time.Minute); !errors.Is(err, ErrSnapshotStale) {
+		t.Fatalf("LoadSnapshot = %v, want ErrSnapshotStale", err)
+	}
+}
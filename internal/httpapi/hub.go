@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"realtime-market-engine/internal/candle"
 	"realtime-market-engine/internal/types"
 )
 
@@ -60,6 +61,48 @@ func (h *Hub) PublishPrice(ev types.PriceEvent) {
 	h.PublishJSON(b)
 }
 
+// PublishCandle publishes a closed candle of the multi-timeframe aggregator.
+func (h *Hub) PublishCandle(c candle.Candle) {
+	h.publishCandle("candle", c)
+}
+
+// PublishCandleRevision publishes a closed candle amended by late ticks.
+func (h *Hub) PublishCandleRevision(c candle.Candle) {
+	h.publishCandle("candle_revised", c)
+}
+
+func (h *Hub) publishCandle(typ string, c candle.Candle) {
+	b, err := json.Marshal(struct {
+		Type string `json:"type"`
+		candle.Candle
+	}{
+		Type:   typ,
+		Candle: c,
+	})
+	if err != nil {
+		return
+	}
+	h.PublishJSON(b)
+}
+
+func (h *Hub) PublishQuote(q types.Quote) {
+	b, err := json.Marshal(struct {
+		Type string `json:"type"`
+		types.Quote
+		Mid       float64 `json:"mid"`
+		SpreadBps float64 `json:"spreadBps"`
+	}{
+		Type:      "quote",
+		Quote:     q,
+		Mid:       q.Mid(),
+		SpreadBps: q.SpreadBps(),
+	})
+	if err != nil {
+		return
+	}
+	h.PublishJSON(b)
+}
+
 func (h *Hub) PublishJSON(b []byte) {
 	select {
 	case h.broadcast <- b:
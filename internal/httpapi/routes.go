@@ -2,11 +2,16 @@ package httpapi
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/orderbook"
 	"realtime-market-engine/internal/store"
+	"realtime-market-engine/internal/types"
 
 	"github.com/gorilla/websocket"
 )
@@ -14,6 +19,9 @@ import (
 type Routes struct {
 	store    *store.PriceStore
 	hub      *Hub
+	books    *orderbook.Books
+	ready    func() string
+	metrics  func() any
 	upgrader websocket.Upgrader
 }
 
@@ -27,10 +35,38 @@ func NewRoutes(store *store.PriceStore, hub *Hub) *Routes {
 	}
 }
 
+// SetOrderBooks enables GET /orderbook/{symbol}.
+func (rt *Routes) SetOrderBooks(books *orderbook.Books) {
+	rt.books = books
+}
+
+// Readiness states reported by GET /ready.
+const (
+	StatusReady     = "ready"
+	StatusWarmingUp = "warming_up"
+	StatusDegraded  = "degraded"
+)
+
+// SetReadiness makes GET /ready report the status returned by ready, with 503
+// for anything but StatusReady.
+func (rt *Routes) SetReadiness(ready func() string) {
+	rt.ready = ready
+}
+
+// SetMetrics enables GET /metrics, which serves metrics as JSON.
+func (rt *Routes) SetMetrics(metrics func() any) {
+	rt.metrics = metrics
+}
+
 func (rt *Routes) Register(mux *http.ServeMux) {
 	mux.HandleFunc("GET /", rt.index)
 	mux.HandleFunc("GET /health", rt.health)
+	mux.HandleFunc("GET /ready", rt.readiness)
+	mux.HandleFunc("GET /metrics", rt.metricsHandler)
 	mux.HandleFunc("GET /prices/", rt.priceBySymbol)
+	mux.HandleFunc("GET /orderbook/{symbol}", rt.orderBook)
+	mux.HandleFunc("GET /history/{symbol}/ticks", rt.tickHistory)
+	mux.HandleFunc("GET /history/{symbol}/candles", rt.candleHistory)
 	mux.HandleFunc("GET /ws", rt.ws)
 }
 
@@ -49,7 +85,7 @@ func (rt *Routes) index(w http.ResponseWriter, r *http.Request) {
     <title>realtime-market-engine</title>
   </head>
   <body style="font-family: ui-sans-serif, system-ui, -apple-system; padding: 16px;">
-    <h2>BTCUSDT Live</h2>
+    <h2>Live</h2>
     <div id="status">Connecting…</div>
     <pre id="out" style="background:#111;color:#eee;padding:12px;border-radius:8px;overflow:auto;max-height:70vh;"></pre>
     <script>
@@ -73,6 +109,27 @@ func (rt *Routes) health(w http.ResponseWriter, _ *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+func (rt *Routes) readiness(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	status := StatusReady
+	if rt.ready != nil {
+		status = rt.ready()
+	}
+	if status != StatusReady {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+func (rt *Routes) metricsHandler(w http.ResponseWriter, _ *http.Request) {
+	if rt.metrics == nil {
+		http.Error(w, "metrics disabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rt.metrics())
+}
+
 func (rt *Routes) priceBySymbol(w http.ResponseWriter, r *http.Request) {
 	symbol := strings.TrimPrefix(r.URL.Path, "/prices/")
 	if symbol == "" {
@@ -80,24 +137,173 @@ func (rt *Routes) priceBySymbol(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ev, ok := rt.store.Get(symbol)
-	if !ok {
+	source := querySource(r)
+	ev, hasTrade := rt.store.Get(source, symbol)
+	q, hasQuote := rt.store.GetQuote(source, symbol)
+	if !hasTrade && !hasQuote {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(struct {
+	type quote struct {
+		Bid       float64   `json:"bid"`
+		BidQty    float64   `json:"bidQty"`
+		Ask       float64   `json:"ask"`
+		AskQty    float64   `json:"askQty"`
+		Mid       float64   `json:"mid"`
+		SpreadBps float64   `json:"spreadBps"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+	resp := struct {
 		Symbol    string    `json:"symbol"`
 		Price     float64   `json:"price"`
 		Timestamp time.Time `json:"timestamp"`
 		Source    string    `json:"source"`
+		Quote     *quote    `json:"quote,omitempty"`
 	}{
-		Symbol:    ev.Symbol,
+		Symbol:    symbol,
 		Price:     ev.Price,
 		Timestamp: ev.Timestamp,
-		Source:    ev.Source,
-	})
+		Source:    source,
+	}
+	if hasQuote {
+		resp.Quote = &quote{
+			Bid:       q.Bid,
+			BidQty:    q.BidQty,
+			Ask:       q.Ask,
+			AskQty:    q.AskQty,
+			Mid:       q.Mid(),
+			SpreadBps: q.SpreadBps(),
+			Timestamp: q.Timestamp,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (rt *Routes) orderBook(w http.ResponseWriter, r *http.Request) {
+	if rt.books == nil {
+		http.Error(w, "order books disabled", http.StatusNotFound)
+		return
+	}
+
+	symbol := strings.ToUpper(r.PathValue("symbol"))
+	depth := 20
+	if v := r.URL.Query().Get("depth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid depth", http.StatusBadRequest)
+			return
+		}
+		depth = n
+	}
+
+	book, ok := rt.books.Get(symbol)
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(book.Top(depth))
+}
+
+// historyQuery is either the last N entries or a [from, to] window. Accepted
+// parameters: last=N, window=1h (ending now), or from/to as RFC3339.
+type historyQuery struct {
+	last     int
+	from, to time.Time
+}
+
+func parseHistoryQuery(r *http.Request) (historyQuery, error) {
+	q := r.URL.Query()
+	if v := q.Get("last"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return historyQuery{}, fmt.Errorf("invalid last")
+		}
+		return historyQuery{last: n}, nil
+	}
+
+	hq := historyQuery{to: time.Now()}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return historyQuery{}, fmt.Errorf("invalid to")
+		}
+		hq.to = t
+	}
+	switch {
+	case q.Get("from") != "":
+		t, err := time.Parse(time.RFC3339, q.Get("from"))
+		if err != nil {
+			return historyQuery{}, fmt.Errorf("invalid from")
+		}
+		hq.from = t
+	case q.Get("window") != "":
+		d, err := time.ParseDuration(q.Get("window"))
+		if err != nil || d <= 0 {
+			return historyQuery{}, fmt.Errorf("invalid window")
+		}
+		hq.from = hq.to.Add(-d)
+	default:
+		return historyQuery{last: 100}, nil
+	}
+	return hq, nil
+}
+
+// querySource is the venue whose prices or history are served, binance
+// unless the source parameter says otherwise.
+func querySource(r *http.Request) string {
+	if v := r.URL.Query().Get("source"); v != "" {
+		return strings.ToLower(v)
+	}
+	return "binance"
+}
+
+func (rt *Routes) tickHistory(w http.ResponseWriter, r *http.Request) {
+	hq, err := parseHistoryQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source, symbol := querySource(r), strings.ToUpper(r.PathValue("symbol"))
+	var ticks []types.PriceEvent
+	if hq.last > 0 {
+		ticks = rt.store.Last(source, symbol, hq.last)
+	} else {
+		ticks = rt.store.Range(source, symbol, hq.from, hq.to)
+	}
+	if ticks == nil {
+		ticks = []types.PriceEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ticks)
+}
+
+func (rt *Routes) candleHistory(w http.ResponseWriter, r *http.Request) {
+	hq, err := parseHistoryQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source, symbol := querySource(r), strings.ToUpper(r.PathValue("symbol"))
+	var candles []candle.Candle
+	if hq.last > 0 {
+		candles = rt.store.LastCandles(source, symbol, hq.last)
+	} else {
+		candles = rt.store.CandleRange(source, symbol, hq.from, hq.to)
+	}
+	if candles == nil {
+		candles = []candle.Candle{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(candles)
 }
 
 func (rt *Routes) ws(w http.ResponseWriter, r *http.Request) {
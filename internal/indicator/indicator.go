@@ -0,0 +1,49 @@
+// Package indicator implements streaming technical indicators. Every update
+// is O(1), amortized for the rolling extremes, so indicators can run on
+// every tick of every symbol.
+package indicator
+
+import (
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
+)
+
+// Bar is one observation. A tick is a bar with Open, High, Low and Close at
+// its price.
+type Bar struct {
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+func FromTick(ev types.PriceEvent) Bar {
+	return Bar{Time: ev.Timestamp, Open: ev.Price, High: ev.Price, Low: ev.Price, Close: ev.Price, Volume: ev.Quantity}
+}
+
+func FromCandle(c candle.Candle) Bar {
+	return Bar{Time: c.End, Open: c.Open, High: c.High, Low: c.Low, Close: c.Close, Volume: c.Volume}
+}
+
+// Indicator is a streaming indicator. Value is the primary output (e.g. the
+// MACD line or the Bollinger middle band); indicators with several outputs
+// have accessors for the others. Value is meaningless until Ready.
+type Indicator interface {
+	Update(b Bar)
+	Value() float64
+	Ready() bool
+}
+
+// UpdateTick feeds a tick to ind.
+func UpdateTick(ind Indicator, ev types.PriceEvent) {
+	ind.Update(FromTick(ev))
+}
+
+// UpdateCandle feeds a candle to ind.
+func UpdateCandle(ind Indicator, c candle.Candle) {
+	ind.Update(FromCandle(c))
+}
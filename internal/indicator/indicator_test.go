@@ -0,0 +1,216 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// closes is the 14-period RSI example series from Wilder's book as used by
+// StockCharts.
+var closes = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+	46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+	43.42, 42.66, 43.13,
+}
+
+func testBars() []Bar {
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	bars := make([]Bar, len(closes))
+	for i, c := range closes {
+		bars[i] = Bar{Time: t0.Add(time.Duration(i) * time.Second), Open: c, High: c, Low: c, Close: c}
+	}
+	return bars
+}
+
+// workedBars are five bars small enough to work every indicator out by hand;
+// the expected values in TestIndicators are exact fractions derived from the
+// textbook formulas, not outputs of this package.
+func workedBars() []Bar {
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	hlcv := [][4]float64{{10, 8, 9, 1}, {11, 9, 10, 1}, {12, 7, 8, 2}, {9, 6, 9, 2}, {13, 9, 12, 3}}
+	bars := make([]Bar, len(hlcv))
+	for i, x := range hlcv {
+		bars[i] = Bar{Time: t0.Add(time.Duration(i) * time.Second), Open: x[2], High: x[0], Low: x[1], Close: x[2], Volume: x[3]}
+	}
+	return bars
+}
+
+func TestIndicators(t *testing.T) {
+	// Closes 9 10 8 9 12; typical prices 9 10 9 8 34/3.
+	tests := []struct {
+		name    string
+		ind     Indicator
+		readyAt int // bars needed until Ready
+		want    map[string]float64
+	}{
+		// (8+9+12)/3
+		{"sma3", NewSMA(3), 3, map[string]float64{"value": 29.0 / 3}},
+		// Seeded at 9, alpha 1/2: 19/2, 35/4, 71/8, 167/16.
+		{"ema3", NewEMA(3), 3, map[string]float64{"value": 167.0 / 16}},
+		// (8*1+9*2+12*3)/6
+		{"wma3", NewWMA(3), 3, map[string]float64{"value": 31.0 / 3}},
+		// Changes +1 -2 +1 +3: first averages 2/3 and 2/3, then gain
+		// (2*2/3+3)/3 = 13/9 and loss (2*2/3)/3 = 4/9; RS 13/4.
+		{"rsi3", NewRSI(3), 4, map[string]float64{"value": 100 - 100/(1+13.0/4)}},
+		// Fast EMA (alpha 2/3) ends at 887/81, slow EMA (alpha 1/2) at
+		// 167/16. The MACD line from bar 3 is -7/36, -5/216, 665/1296;
+		// its signal EMA (alpha 2/3) -7/36, -13/162, 613/1944.
+		{"macd", NewMACD(2, 3, 2), 4, map[string]float64{
+			"value":  665.0 / 1296,
+			"signal": 613.0 / 1944,
+			"hist":   769.0 / 3888,
+		}},
+		// Window 8 9 12: mean 29/3, squared deviations 25/9+4/9+49/9,
+		// population variance 26/9.
+		{"bollinger", NewBollinger(3, 2), 3, map[string]float64{
+			"value": 29.0 / 3,
+			"upper": 29.0/3 + 2*math.Sqrt(26)/3,
+			"lower": 29.0/3 - 2*math.Sqrt(26)/3,
+		}},
+		// True ranges 2 2 5 3 4: first average 3, then (2*3+3)/3 = 3 and
+		// (2*3+4)/3.
+		{"atr3", NewATR(3), 3, map[string]float64{"value": 10.0 / 3}},
+		// %K over 3 bars from bar 3: (8-7)/(12-7) = 20, (9-6)/(12-6) = 50,
+		// (12-6)/(13-6) = 600/7; %D is the mean of the last two.
+		{"stochastic", NewStochastic(3, 2), 4, map[string]float64{
+			"value": 600.0 / 7,
+			"d":     (50 + 600.0/7) / 2,
+		}},
+		// +1 -2 +2 +3
+		{"obv", NewOBV(), 1, map[string]float64{"value": 4}},
+		// The window ending at bar 5 drops bars 1 to 3: (8*2+34/3*3)/5.
+		{"vwap", NewVWAP(2 * time.Second), 1, map[string]float64{"value": 10}},
+		// (9+10+9*2+8*2+34/3*3)/9
+		{"vwap-cumulative", NewVWAP(0), 1, map[string]float64{"value": 29.0 / 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, b := range workedBars() {
+				tt.ind.Update(b)
+				if want := i+1 >= tt.readyAt; tt.ind.Ready() != want {
+					t.Fatalf("after %d bars Ready() = %v, want %v", i+1, !want, want)
+				}
+			}
+			got := map[string]float64{"value": tt.ind.Value()}
+			switch ind := tt.ind.(type) {
+			case *MACD:
+				got["signal"], got["hist"] = ind.Signal(), ind.Histogram()
+			case *Bollinger:
+				got["upper"], got["lower"] = ind.Upper(), ind.Lower()
+			case *Stochastic:
+				got["d"] = ind.D()
+			}
+			for k, want := range tt.want {
+				if math.Abs(got[k]-want) > 1e-9 {
+					t.Errorf("%s = %.12f, want %.12f", k, got[k], want)
+				}
+			}
+		})
+	}
+}
+
+// TestRSISeries checks RSI(14) against the values StockCharts publishes for
+// the example series. Its spreadsheet rounds intermediate values, and the
+// published figures are up to 0.07 off the exact computation.
+func TestRSISeries(t *testing.T) {
+	want := []float64{
+		70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+	}
+	rsi := NewRSI(14)
+	var got []float64
+	for _, b := range testBars() {
+		rsi.Update(b)
+		if rsi.Ready() {
+			got = append(got, rsi.Value())
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 0.08 {
+			t.Errorf("RSI[%d] = %.4f, want %.2f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtremesMatchesScan(t *testing.T) {
+	const n = 7
+	var x Extremes
+	var vals []float64
+	for i := 0; i < 500; i++ {
+		v := math.Sin(float64(i)*0.37) * float64(i%13)
+		vals = append(vals, v)
+		x.Push(v, v)
+		if x.Len() > n {
+			x.PopFront()
+		}
+
+		w := vals[max(0, len(vals)-n):]
+		hi, lo := w[0], w[0]
+		for _, v := range w {
+			hi, lo = max(hi, v), min(lo, v)
+		}
+		if x.High() != hi || x.Low() != lo {
+			t.Fatalf("step %d: got %v/%v, want %v/%v", i, x.High(), x.Low(), hi, lo)
+		}
+	}
+}
+
+func TestStatsMatchesScan(t *testing.T) {
+	var s Stats
+	var vals []float64
+	for i := 0; i < 500; i++ {
+		v := 100 + math.Sin(float64(i)*0.37)*float64(i%13)
+		vals = append(vals, v)
+		s.Add(v)
+		// Let the window size vary between 5 and 11 values.
+		for len(vals) > 5+i%7 {
+			s.Remove(vals[0])
+			vals = vals[1:]
+		}
+
+		var sum, sq float64
+		for _, v := range vals {
+			sum += v
+		}
+		mean := sum / float64(len(vals))
+		for _, v := range vals {
+			sq += (v - mean) * (v - mean)
+		}
+		sd := math.Sqrt(sq / float64(len(vals)))
+		if s.Len() != len(vals) || math.Abs(s.Mean()-mean) > 1e-9 || math.Abs(s.StdDev()-sd) > 1e-6 {
+			t.Fatalf("step %d: got n=%d mean=%v sd=%v, want %d %v %v", i, s.Len(), s.Mean(), s.StdDev(), len(vals), mean, sd)
+		}
+	}
+}
+
+// TestStatsPriceLevel slides a 20-value window over two million prices
+// between 65k and 70k and ends on a quiet stretch alternating 0.005 around
+// its mean, whose standard deviation is 0.005.
+func TestStatsPriceLevel(t *testing.T) {
+	const n, steps = 20, 2_000_000
+	var s Stats
+	vals := make([]float64, 0, steps+n)
+	for i := 0; i < steps; i++ {
+		v := 67500 + 2500*math.Sin(float64(i)*1e-5) + 3*math.Sin(float64(i)*0.7)
+		vals = append(vals, v)
+		s.Add(v)
+		if s.Len() > n {
+			s.Remove(vals[i-n])
+		}
+	}
+	for i := 0; i < n; i++ {
+		v := 67123.45 + 0.005*float64(1-2*(i%2))
+		vals = append(vals, v)
+		s.Add(v)
+		s.Remove(vals[len(vals)-1-n])
+	}
+	if math.Abs(s.Mean()-67123.45) > 1e-6 || math.Abs(s.StdDev()-0.005) > 1e-5 {
+		t.Fatalf("mean %v stddev %v, want 67123.45 and 0.005", s.Mean(), s.StdDev())
+	}
+}
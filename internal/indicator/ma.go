@@ -0,0 +1,106 @@
+package indicator
+
+// SMA is the simple moving average of the close over n bars.
+type SMA struct {
+	w   *window
+	sum float64
+}
+
+func NewSMA(n int) *SMA {
+	return &SMA{w: newWindow(n)}
+}
+
+func (s *SMA) Update(b Bar) { s.Add(b.Close) }
+
+// Add feeds a value rather than a bar.
+func (s *SMA) Add(x float64) {
+	if old, ok := s.w.push(x); ok {
+		s.sum -= old
+	}
+	s.sum += x
+}
+
+func (s *SMA) Value() float64 {
+	if s.w.size == 0 {
+		return 0
+	}
+	return s.sum / float64(s.w.size)
+}
+
+func (s *SMA) Ready() bool { return s.w.full() }
+
+// EMA is the exponential moving average of the close with alpha 2/(n+1).
+// It is seeded with the first value and is Ready after n bars. The trend
+// detector runs its EMAs on it.
+type EMA struct {
+	n     int
+	alpha float64
+	count int
+	value float64
+}
+
+func NewEMA(n int) *EMA {
+	if n < 1 {
+		n = 1
+	}
+	return &EMA{n: n, alpha: 2 / (float64(n) + 1)}
+}
+
+func (e *EMA) Update(b Bar) { e.Add(b.Close) }
+
+// Add feeds a value rather than a bar.
+func (e *EMA) Add(x float64) { e.AddWeighted(x, e.alpha) }
+
+// AddWeighted feeds x with weight alpha instead of 2/(n+1), e.g. one derived
+// from the time since the previous value. The first value seeds the EMA.
+func (e *EMA) AddWeighted(x, alpha float64) {
+	if e.count == 0 {
+		e.value = x
+	} else {
+		e.value += alpha * (x - e.value)
+	}
+	e.count++
+}
+
+// Seed restarts the EMA at x as if x were its first value.
+func (e *EMA) Seed(x float64) {
+	e.value, e.count = x, 1
+}
+
+func (e *EMA) Value() float64 { return e.value }
+
+func (e *EMA) Ready() bool { return e.count >= e.n }
+
+// WMA is the linearly weighted moving average of the close over n bars, the
+// newest bar weighing n.
+type WMA struct {
+	w        *window
+	sum      float64
+	weighted float64
+}
+
+func NewWMA(n int) *WMA {
+	return &WMA{w: newWindow(n)}
+}
+
+func (m *WMA) Update(b Bar) {
+	x := b.Close
+	if old, ok := m.w.push(x); ok {
+		// Every kept value loses one weight; old drops from weight 1 to 0.
+		m.weighted += float64(m.w.size)*x - m.sum
+		m.sum += x - old
+		return
+	}
+	m.weighted += float64(m.w.size) * x
+	m.sum += x
+}
+
+func (m *WMA) Value() float64 {
+	k := float64(m.w.size)
+	if k == 0 {
+		return 0
+	}
+	return m.weighted / (k * (k + 1) / 2)
+}
+
+func (m *WMA) Ready() bool { return m.w.full() }
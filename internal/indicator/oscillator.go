@@ -0,0 +1,117 @@
+package indicator
+
+// RSI is Wilder's relative strength index of the close over n bars, from 0
+// to 100. The first average is the mean of n changes, later ones use
+// Wilder's smoothing.
+type RSI struct {
+	n                int
+	hasPrev          bool
+	prev             float64
+	count            int
+	avgGain, avgLoss float64
+}
+
+func NewRSI(n int) *RSI {
+	if n < 1 {
+		n = 1
+	}
+	return &RSI{n: n}
+}
+
+func (r *RSI) Update(b Bar) {
+	if !r.hasPrev {
+		r.prev, r.hasPrev = b.Close, true
+		return
+	}
+	change := b.Close - r.prev
+	r.prev = b.Close
+	gain, loss := max(change, 0), max(-change, 0)
+
+	r.count++
+	if r.count <= r.n {
+		r.avgGain += gain / float64(r.n)
+		r.avgLoss += loss / float64(r.n)
+		return
+	}
+	n := float64(r.n)
+	r.avgGain = (r.avgGain*(n-1) + gain) / n
+	r.avgLoss = (r.avgLoss*(n-1) + loss) / n
+}
+
+func (r *RSI) Value() float64 {
+	switch {
+	case r.avgLoss == 0 && r.avgGain == 0:
+		return 50
+	case r.avgLoss == 0:
+		return 100
+	}
+	return 100 - 100/(1+r.avgGain/r.avgLoss)
+}
+
+func (r *RSI) Ready() bool { return r.count >= r.n }
+
+// MACD is the difference of a fast and a slow EMA of the close, with a
+// signal EMA of that difference. The signal EMA starts once the slow EMA is
+// ready, so MACD(12, 26, 9) is ready after 34 bars. Value is the MACD line.
+type MACD struct {
+	fast, slow, signal *EMA
+}
+
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+func (m *MACD) Update(b Bar) {
+	m.fast.Add(b.Close)
+	m.slow.Add(b.Close)
+	if m.slow.Ready() {
+		m.signal.Add(m.Value())
+	}
+}
+
+func (m *MACD) Value() float64 { return m.fast.Value() - m.slow.Value() }
+
+// Signal returns the signal line.
+func (m *MACD) Signal() float64 { return m.signal.Value() }
+
+// Histogram returns the MACD line minus the signal line.
+func (m *MACD) Histogram() float64 { return m.Value() - m.Signal() }
+
+func (m *MACD) Ready() bool { return m.slow.Ready() && m.signal.Ready() }
+
+// Stochastic is the stochastic oscillator: %K places the close within the
+// high-low range of the last k bars, %D is the d-bar SMA of %K. Value is %K.
+type Stochastic struct {
+	ext Extremes
+	kn  int
+	n   int
+	k   float64
+	d   *SMA
+}
+
+func NewStochastic(k, d int) *Stochastic {
+	return &Stochastic{kn: max(k, 1), d: NewSMA(d)}
+}
+
+func (s *Stochastic) Update(b Bar) {
+	s.ext.Push(b.High, b.Low)
+	if s.ext.Len() > s.kn {
+		s.ext.PopFront()
+	}
+	s.n++
+	hi, lo := s.ext.High(), s.ext.Low()
+	s.k = 50
+	if hi > lo {
+		s.k = 100 * (b.Close - lo) / (hi - lo)
+	}
+	if s.n >= s.kn {
+		s.d.Add(s.k)
+	}
+}
+
+func (s *Stochastic) Value() float64 { return s.k }
+
+// D returns %D.
+func (s *Stochastic) D() float64 { return s.d.Value() }
+
+func (s *Stochastic) Ready() bool { return s.d.Ready() }
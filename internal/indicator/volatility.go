@@ -0,0 +1,75 @@
+package indicator
+
+import "math"
+
+// Bollinger holds Bollinger Bands: the n-bar SMA of the close and bands k
+// population standard deviations above and below it. Value is the middle band.
+type Bollinger struct {
+	w     *window
+	k     float64
+	stats Stats
+}
+
+func NewBollinger(n int, k float64) *Bollinger {
+	return &Bollinger{w: newWindow(n), k: k}
+}
+
+func (bb *Bollinger) Update(b Bar) {
+	if old, ok := bb.w.push(b.Close); ok {
+		bb.stats.Remove(old)
+	}
+	bb.stats.Add(b.Close)
+}
+
+func (bb *Bollinger) Value() float64 { return bb.stats.Mean() }
+
+// StdDev returns the population standard deviation of the window.
+func (bb *Bollinger) StdDev() float64 { return bb.stats.StdDev() }
+
+func (bb *Bollinger) Upper() float64 { return bb.Value() + bb.k*bb.StdDev() }
+
+func (bb *Bollinger) Lower() float64 { return bb.Value() - bb.k*bb.StdDev() }
+
+func (bb *Bollinger) Ready() bool { return bb.w.full() }
+
+// TrueRange is the range of a bar with the given high and low, extended to
+// the previous close.
+func TrueRange(high, low, prevClose float64) float64 {
+	return max(high-low, math.Abs(high-prevClose), math.Abs(low-prevClose))
+}
+
+// ATR is Wilder's average true range over n bars. The first average is the
+// mean of n true ranges; the first bar's true range is its high-low range.
+type ATR struct {
+	n         int
+	hasPrev   bool
+	prevClose float64
+	count     int
+	value     float64
+}
+
+func NewATR(n int) *ATR {
+	if n < 1 {
+		n = 1
+	}
+	return &ATR{n: n}
+}
+
+func (a *ATR) Update(b Bar) {
+	tr := b.High - b.Low
+	if a.hasPrev {
+		tr = TrueRange(b.High, b.Low, a.prevClose)
+	}
+	a.prevClose, a.hasPrev = b.Close, true
+
+	a.count++
+	if a.count <= a.n {
+		a.value += (tr - a.value) / float64(a.count)
+		return
+	}
+	a.value = (a.value*float64(a.n-1) + tr) / float64(a.n)
+}
+
+func (a *ATR) Value() float64 { return a.value }
+
+func (a *ATR) Ready() bool { return a.count >= a.n }
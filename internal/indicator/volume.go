@@ -0,0 +1,80 @@
+package indicator
+
+import "time"
+
+// OBV is the on-balance volume: the running sum of volume, added on up
+// closes and subtracted on down closes.
+type OBV struct {
+	hasPrev bool
+	prev    float64
+	value   float64
+}
+
+func NewOBV() *OBV { return &OBV{} }
+
+func (o *OBV) Update(b Bar) {
+	if o.hasPrev {
+		switch {
+		case b.Close > o.prev:
+			o.value += b.Volume
+		case b.Close < o.prev:
+			o.value -= b.Volume
+		}
+	}
+	o.prev, o.hasPrev = b.Close, true
+}
+
+func (o *OBV) Value() float64 { return o.value }
+
+func (o *OBV) Ready() bool { return o.hasPrev }
+
+// VWAP is the volume-weighted average price of the bars within a trailing
+// time window, using the typical price (high+low+close)/3; for ticks that is
+// the trade price. Bars older than the window ending at the latest bar drop
+// out; a window of zero or less keeps every bar, a cumulative VWAP.
+type VWAP struct {
+	window     time.Duration
+	bars       []vwapBar
+	head       int
+	pv, volume float64
+}
+
+type vwapBar struct {
+	t      time.Time
+	pv, vo float64
+}
+
+func NewVWAP(window time.Duration) *VWAP {
+	return &VWAP{window: window}
+}
+
+func (v *VWAP) Update(b Bar) {
+	typical := (b.High + b.Low + b.Close) / 3
+	e := vwapBar{t: b.Time, pv: typical * b.Volume, vo: b.Volume}
+	v.pv += e.pv
+	v.volume += e.vo
+	if v.window <= 0 {
+		return
+	}
+	v.bars = append(v.bars, e)
+
+	cut := b.Time.Add(-v.window)
+	for v.head < len(v.bars) && !v.bars[v.head].t.After(cut) {
+		v.pv -= v.bars[v.head].pv
+		v.volume -= v.bars[v.head].vo
+		v.head++
+	}
+	if v.head > len(v.bars)/2 {
+		v.bars = append(v.bars[:0], v.bars[v.head:]...)
+		v.head = 0
+	}
+}
+
+func (v *VWAP) Value() float64 {
+	if v.volume <= 0 {
+		return 0
+	}
+	return v.pv / v.volume
+}
+
+func (v *VWAP) Ready() bool { return v.volume > 0 }
@@ -0,0 +1,155 @@
+package indicator
+
+import "math"
+
+// window holds the last n values.
+type window struct {
+	buf  []float64
+	head int // index of the oldest value once full
+	size int
+}
+
+func newWindow(n int) *window {
+	if n < 1 {
+		n = 1
+	}
+	return &window{buf: make([]float64, n)}
+}
+
+func (w *window) full() bool {
+	return w.size == len(w.buf)
+}
+
+// push adds x and returns the value it evicted, if any.
+func (w *window) push(x float64) (float64, bool) {
+	if !w.full() {
+		w.buf[(w.head+w.size)%len(w.buf)] = x
+		w.size++
+		return 0, false
+	}
+	old := w.buf[w.head]
+	w.buf[w.head] = x
+	w.head = (w.head + 1) % len(w.buf)
+	return old, true
+}
+
+// Extremes tracks the highest high and lowest low of a sliding window with
+// monotonic deques, amortized O(1) per update. The caller evicts the oldest
+// value with PopFront, so the window can be bounded by a count or by time.
+type Extremes struct {
+	next     int // sequence number of the next value
+	oldest   int // sequence number of the oldest value
+	max, min deque
+}
+
+type entry struct {
+	seq int
+	v   float64
+}
+
+// deque is a slice-backed double-ended queue; popped front space is
+// reclaimed when the slice grows.
+type deque struct {
+	buf  []entry
+	head int
+}
+
+func (d *deque) empty() bool  { return d.head == len(d.buf) }
+func (d *deque) front() entry { return d.buf[d.head] }
+func (d *deque) back() entry  { return d.buf[len(d.buf)-1] }
+func (d *deque) popFront()    { d.head++ }
+func (d *deque) popBack()     { d.buf = d.buf[:len(d.buf)-1] }
+func (d *deque) pushBack(e entry) {
+	if d.head > 0 && len(d.buf) == cap(d.buf) {
+		n := copy(d.buf, d.buf[d.head:])
+		d.buf, d.head = d.buf[:n], 0
+	}
+	d.buf = append(d.buf, e)
+}
+
+// Len returns the number of values in the window.
+func (x *Extremes) Len() int { return x.next - x.oldest }
+
+// Push adds a value's high and low as the newest of the window.
+func (x *Extremes) Push(high, low float64) {
+	for !x.max.empty() && x.max.back().v <= high {
+		x.max.popBack()
+	}
+	x.max.pushBack(entry{x.next, high})
+	for !x.min.empty() && x.min.back().v >= low {
+		x.min.popBack()
+	}
+	x.min.pushBack(entry{x.next, low})
+	x.next++
+}
+
+// PopFront evicts the oldest value.
+func (x *Extremes) PopFront() {
+	if x.Len() == 0 {
+		return
+	}
+	x.oldest++
+	for !x.max.empty() && x.max.front().seq < x.oldest {
+		x.max.popFront()
+	}
+	for !x.min.empty() && x.min.front().seq < x.oldest {
+		x.min.popFront()
+	}
+}
+
+// High returns the highest high in the window; the window must not be empty.
+func (x *Extremes) High() float64 { return x.max.front().v }
+
+// Low returns the lowest low in the window; the window must not be empty.
+func (x *Extremes) Low() float64 { return x.min.front().v }
+
+// Stats keeps the mean and population standard deviation of a window of
+// values with Welford's update, run backwards to remove a value; the caller
+// adds and removes the values. The mean is summed with Kahan compensation, so
+// small deviations around large values such as BTC prices stay accurate over
+// millions of updates, where running sums of squares cancel to nothing.
+type Stats struct {
+	n        int
+	mean, m2 float64 // m2 is the sum of squared deviations from the mean
+	c        float64 // compensation: the mean is mean - c
+}
+
+// shift moves the mean by delta.
+func (s *Stats) shift(delta float64) {
+	y := delta - s.c
+	t := s.mean + y
+	s.c = (t - s.mean) - y
+	s.mean = t
+}
+
+func (s *Stats) Add(x float64) {
+	s.n++
+	d := (x - s.mean) + s.c
+	s.shift(d / float64(s.n))
+	s.m2 += d * ((x - s.mean) + s.c)
+}
+
+// Remove takes x, which must have been added, out of the window.
+func (s *Stats) Remove(x float64) {
+	if s.n--; s.n <= 0 {
+		// Empty again; reset to shed rounding drift.
+		*s = Stats{}
+		return
+	}
+	d := (x - s.mean) + s.c
+	s.shift(-d / float64(s.n))
+	s.m2 -= d * ((x - s.mean) + s.c)
+}
+
+func (s *Stats) Len() int     { return s.n }
+func (s *Stats) Sum() float64 { return s.Mean() * float64(s.n) }
+
+func (s *Stats) Mean() float64 { return s.mean - s.c }
+
+func (s *Stats) StdDev() float64 {
+	if s.n == 0 {
+		return 0
+	}
+	// Clamp the rounding error of the removals.
+	return math.Sqrt(max(s.m2, 0) / float64(s.n))
+}
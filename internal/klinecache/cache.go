@@ -0,0 +1,298 @@
+package klinecache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/market"
+)
+
+// ErrMissing is returned in offline mode when the cache does not cover the request.
+var ErrMissing = errors.New("klinecache: range not cached")
+
+// Fetcher is the upstream kline source, typically *binance.KlineFetcher.
+type Fetcher interface {
+	FetchKlines(ctx context.Context, symbol, interval string, start, end time.Time) ([]candle.Candle, error)
+}
+
+// Cache stores klines on disk, one file per symbol, interval and UTC day (or
+// month for intervals of an hour and more), and only asks the upstream
+// fetcher for sub-ranges it has not seen yet. Covered ranges are tracked
+// separately from the candles so that periods without trading are not
+// fetched again. A request only reads and rewrites the chunks it overlaps.
+type Cache struct {
+	dir     string
+	fetcher Fetcher
+	offline bool
+
+	mu sync.Mutex
+}
+
+func New(dir string, fetcher Fetcher, offline bool) *Cache {
+	return &Cache{dir: dir, fetcher: fetcher, offline: offline}
+}
+
+type span struct {
+	Start int64 `json:"start"` // open time, ms, inclusive
+	End   int64 `json:"end"`   // open time, ms, exclusive
+}
+
+// file is one chunk; its spans and candles lie within the chunk.
+type file struct {
+	Symbol   string          `json:"symbol"`
+	Interval string          `json:"interval"`
+	Covered  []span          `json:"covered"`
+	Candles  []candle.Candle `json:"candles"`
+}
+
+type chunk struct {
+	span
+	path    string
+	f       *file
+	changed bool
+}
+
+// FetchKlines returns candles whose open time is within [start, end], serving
+// cached data and fetching only the missing parts.
+func (c *Cache) FetchKlines(ctx context.Context, symbol, interval string, start, end time.Time) ([]candle.Candle, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	want := span{Start: start.UnixMilli(), End: end.UnixMilli() + 1}
+	chunks, err := c.load(symbol, interval, want)
+	if err != nil {
+		return nil, err
+	}
+	var covered []span
+	for _, ch := range chunks {
+		for _, s := range ch.f.Covered {
+			covered = addSpan(covered, s)
+		}
+	}
+	missing := subtract(want, covered)
+
+	if len(missing) > 0 && c.offline {
+		return nil, fmt.Errorf("%w: %s %s %d missing range(s), first from %s", ErrMissing, symbol, interval, len(missing),
+			time.UnixMilli(missing[0].Start).UTC().Format(time.RFC3339))
+	}
+
+	for _, m := range missing {
+		fetched, err := c.fetcher.FetchKlines(ctx, symbol, interval, time.UnixMilli(m.Start), time.UnixMilli(m.End-1))
+		if err != nil {
+			_ = saveChanged(chunks)
+			return nil, err
+		}
+
+		// The still-open candle is neither cached nor returned, and the time
+		// from its open onwards stays uncovered.
+		covered := m
+		kept := fetched[:0]
+		for _, k := range fetched {
+			if !k.Closed {
+				if k.Start.UnixMilli() < covered.End {
+					covered.End = k.Start.UnixMilli()
+				}
+				continue
+			}
+			kept = append(kept, k)
+		}
+		if now := time.Now().UnixMilli(); covered.End > now {
+			covered.End = now
+		}
+
+		for _, ch := range chunks {
+			var in []candle.Candle
+			for _, k := range kept {
+				if ms := k.Start.UnixMilli(); ms >= ch.Start && ms < ch.End {
+					in = append(in, k)
+				}
+			}
+			s := span{Start: max(covered.Start, ch.Start), End: min(covered.End, ch.End)}
+			if len(in) == 0 && s.End <= s.Start {
+				continue
+			}
+			ch.f.Candles = mergeCandles(ch.f.Candles, in)
+			if s.End > s.Start {
+				ch.f.Covered = addSpan(ch.f.Covered, s)
+			}
+			ch.changed = true
+		}
+	}
+
+	if err := saveChanged(chunks); err != nil {
+		return nil, err
+	}
+
+	out := make([]candle.Candle, 0)
+	for _, ch := range chunks {
+		for _, k := range ch.f.Candles {
+			ms := k.Start.UnixMilli()
+			if ms >= want.Start && ms < want.End {
+				out = append(out, k)
+			}
+		}
+	}
+	return out, nil
+}
+
+// load reads the chunks overlapping want, in time order. Missing chunk files
+// load as empty.
+func (c *Cache) load(symbol, interval string, want span) ([]*chunk, error) {
+	monthly := true
+	if d, err := market.ParseInterval(interval); err == nil && d < time.Hour {
+		monthly = false
+	}
+	dir := filepath.Join(c.dir, strings.ToUpper(symbol)+"_"+interval)
+
+	var out []*chunk
+	t := time.UnixMilli(want.Start).UTC()
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if monthly {
+		t = t.AddDate(0, 0, 1-t.Day())
+	}
+	for t.UnixMilli() < want.End {
+		next, name := t.AddDate(0, 0, 1), t.Format("2006-01-02")
+		if monthly {
+			next, name = t.AddDate(0, 1, 0), t.Format("2006-01")
+		}
+		path := filepath.Join(dir, name+".json")
+		f, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		f.Symbol, f.Interval = symbol, interval
+		out = append(out, &chunk{span: span{Start: t.UnixMilli(), End: next.UnixMilli()}, path: path, f: f})
+		t = next
+	}
+	return out, nil
+}
+
+func saveChanged(chunks []*chunk) error {
+	for _, ch := range chunks {
+		if !ch.changed {
+			continue
+		}
+		if err := save(ch.path, ch.f); err != nil {
+			return err
+		}
+		ch.changed = false
+	}
+	return nil
+}
+
+func load(path string) (*file, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &file{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("klinecache: corrupt %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// save writes f atomically via a temp file and rename.
+func save(path string, f *file) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// subtract returns the parts of want not covered by the sorted, disjoint spans.
+func subtract(want span, covered []span) []span {
+	var out []span
+	cur := want.Start
+	for _, c := range covered {
+		if c.End <= cur {
+			continue
+		}
+		if c.Start >= want.End {
+			break
+		}
+		if c.Start > cur {
+			out = append(out, span{Start: cur, End: c.Start})
+		}
+		cur = c.End
+		if cur >= want.End {
+			return out
+		}
+	}
+	if cur < want.End {
+		out = append(out, span{Start: cur, End: want.End})
+	}
+	return out
+}
+
+// addSpan inserts s and merges overlapping or adjacent spans.
+func addSpan(spans []span, s span) []span {
+	spans = append(spans, s)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	out := spans[:1]
+	for _, x := range spans[1:] {
+		last := &out[len(out)-1]
+		if x.Start <= last.End {
+			if x.End > last.End {
+				last.End = x.End
+			}
+			continue
+		}
+		out = append(out, x)
+	}
+	return out
+}
+
+// mergeCandles merges b into a, keyed by open time; b wins on conflicts.
+func mergeCandles(a, b []candle.Candle) []candle.Candle {
+	if len(b) == 0 {
+		return a
+	}
+	byStart := make(map[int64]candle.Candle, len(a)+len(b))
+	for _, k := range a {
+		byStart[k.Start.UnixMilli()] = k
+	}
+	for _, k := range b {
+		byStart[k.Start.UnixMilli()] = k
+	}
+	out := make([]candle.Candle, 0, len(byStart))
+	for _, k := range byStart {
+		out = append(out, k)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
@@ -0,0 +1,112 @@
+package klinecache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+)
+
+type fakeFetcher struct {
+	calls [][2]time.Time
+}
+
+func (f *fakeFetcher) FetchKlines(_ context.Context, symbol, _ string, start, end time.Time) ([]candle.Candle, error) {
+	f.calls = append(f.calls, [2]time.Time{start, end})
+	var out []candle.Candle
+	for t := start.Truncate(time.Minute); !t.After(end); t = t.Add(time.Minute) {
+		if t.Before(start) {
+			continue
+		}
+		out = append(out, candle.Candle{Symbol: symbol, Start: t, End: t.Add(time.Minute - time.Millisecond), Close: float64(t.Unix()), Closed: true})
+	}
+	return out, nil
+}
+
+func TestCacheFetchesOnlyMissingRanges(t *testing.T) {
+	dir := t.TempDir()
+	up := &fakeFetcher{}
+	c := New(dir, up, false)
+	ctx := context.Background()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := c.FetchKlines(ctx, "BTCUSDT", "1m", t0.Add(10*time.Minute), t0.Add(20*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 11 || len(up.calls) != 1 {
+		t.Fatalf("got %d candles with %d calls, want 11 with 1", len(got), len(up.calls))
+	}
+
+	// Overlapping request: only the head and tail are fetched.
+	got, err = c.FetchKlines(ctx, "BTCUSDT", "1m", t0, t0.Add(30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 31 {
+		t.Fatalf("got %d candles, want 31", len(got))
+	}
+	if len(up.calls) != 3 {
+		t.Fatalf("got %d upstream calls, want 3", len(up.calls))
+	}
+	if !up.calls[1][1].Before(t0.Add(10*time.Minute)) || up.calls[2][0].Before(t0.Add(20*time.Minute+time.Millisecond)) {
+		t.Errorf("unexpected fetched ranges: %v", up.calls[1:])
+	}
+	for i := 1; i < len(got); i++ {
+		if !got[i].Start.Equal(got[i-1].Start.Add(time.Minute)) {
+			t.Fatalf("candles not contiguous at %d", i)
+		}
+	}
+
+	// A fresh cache on the same dir serves everything offline.
+	off := New(dir, &fakeFetcher{}, true)
+	got, err = off.FetchKlines(ctx, "BTCUSDT", "1m", t0.Add(5*time.Minute), t0.Add(25*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 21 {
+		t.Errorf("got %d candles offline, want 21", len(got))
+	}
+
+	_, err = off.FetchKlines(ctx, "BTCUSDT", "1m", t0, t0.Add(time.Hour))
+	if !errors.Is(err, ErrMissing) {
+		t.Errorf("got %v, want ErrMissing", err)
+	}
+}
+
+func TestCacheChunksByDay(t *testing.T) {
+	dir := t.TempDir()
+	up := &fakeFetcher{}
+	c := New(dir, up, false)
+	ctx := context.Background()
+	t0 := time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC)
+
+	// One upstream call across midnight, stored as two daily chunks.
+	got, err := c.FetchKlines(ctx, "BTCUSDT", "1m", t0, t0.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 121 || len(up.calls) != 1 {
+		t.Fatalf("got %d candles with %d calls, want 121 with 1", len(got), len(up.calls))
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "BTCUSDT_1m", "*.json"))
+	if len(files) != 2 || filepath.Base(files[0]) != "2026-01-01.json" || filepath.Base(files[1]) != "2026-01-02.json" {
+		t.Fatalf("chunk files = %v", files)
+	}
+
+	// A request within one day only reads that day's chunk.
+	if err := os.WriteFile(files[0], []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = New(dir, nil, true).FetchKlines(ctx, "BTCUSDT", "1m", t0.Add(time.Hour), t0.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 61 {
+		t.Fatalf("got %d candles, want 61", len(got))
+	}
+}
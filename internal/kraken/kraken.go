@@ -0,0 +1,231 @@
+package kraken
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/market"
+	"realtime-market-engine/internal/types"
+	"realtime-market-engine/internal/wsstream"
+
+	"github.com/gorilla/websocket"
+)
+
+const wsURL = "wss://ws.kraken.com"
+
+// Kraken keeps legacy asset codes for some bases and lists USD books where
+// Binance lists USDT ones.
+var (
+	toVenueAsset = map[string]string{"BTC": "XBT", "DOGE": "XDG"}
+	toVenueQuote = map[string]string{"USDT": "USD"}
+)
+
+// Pair converts a canonical symbol (BTCUSDT) to a Kraken WebSocket pair (XBT/USD).
+func Pair(symbol string) (string, error) {
+	inst, err := market.ParseSymbol(symbol)
+	if err != nil {
+		return "", err
+	}
+	base, quote := inst.Base, inst.Quote
+	if v, ok := toVenueAsset[base]; ok {
+		base = v
+	}
+	if v, ok := toVenueQuote[quote]; ok {
+		quote = v
+	} else if v, ok := toVenueAsset[quote]; ok {
+		quote = v
+	}
+	return base + "/" + quote, nil
+}
+
+// Symbol converts a Kraken WebSocket pair (XBT/USD) to a canonical symbol (BTCUSDT).
+func Symbol(pair string) (string, error) {
+	base, quote, ok := strings.Cut(strings.ToUpper(pair), "/")
+	if !ok || base == "" || quote == "" {
+		return "", fmt.Errorf("invalid pair %q", pair)
+	}
+	base = fromVenue(toVenueAsset, base)
+	if q := fromVenue(toVenueQuote, quote); q != quote {
+		quote = q
+	} else {
+		quote = fromVenue(toVenueAsset, quote)
+	}
+	return base + quote, nil
+}
+
+func fromVenue(m map[string]string, code string) string {
+	for canonical, venue := range m {
+		if code == venue {
+			return canonical
+		}
+	}
+	return code
+}
+
+type Option func(*Source)
+
+// WithURL overrides the WebSocket URL.
+func WithURL(u string) Option {
+	return func(s *Source) { s.url = u }
+}
+
+// WithDialer sets the dialer used for WebSocket connections.
+func WithDialer(d *websocket.Dialer) Option {
+	return func(s *Source) {
+		if d != nil {
+			s.dialer = d
+		}
+	}
+}
+
+// Source streams public trades from the Kraken WebSocket trade channel.
+type Source struct {
+	url    string
+	dialer *websocket.Dialer
+}
+
+func NewSource(opts ...Option) *Source {
+	s := &Source{url: wsURL, dialer: websocket.DefaultDialer}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Source) Name() string {
+	return "kraken"
+}
+
+type subscribeMessage struct {
+	Event        string   `json:"event"`
+	Pair         []string `json:"pair"`
+	Subscription struct {
+		Name string `json:"name"`
+	} `json:"subscription"`
+}
+
+func (s *Source) Trades(ctx context.Context, symbols []string) (<-chan types.PriceEvent, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("at least one symbol required")
+	}
+
+	canonical := make(map[string]string, len(symbols))
+	pairs := make([]string, 0, len(symbols))
+	for _, sym := range symbols {
+		p, err := Pair(sym)
+		if err != nil {
+			return nil, err
+		}
+		canonical[p] = strings.ToUpper(sym)
+		pairs = append(pairs, p)
+	}
+
+	ch := make(chan types.PriceEvent, 100*len(symbols))
+
+	subscribe := func(conn *websocket.Conn) error {
+		var m subscribeMessage
+		m.Event = "subscribe"
+		m.Pair = pairs
+		m.Subscription.Name = "trade"
+		return conn.WriteJSON(m)
+	}
+
+	go func() {
+		defer close(ch)
+
+		wsstream.Run(ctx, s.dialer, s.url, "kraken trade: "+strings.Join(pairs, ","), subscribe, func(message []byte) bool {
+			// Heartbeats and status messages are objects; channel data are arrays.
+			if !bytes.HasPrefix(bytes.TrimSpace(message), []byte("[")) {
+				return true
+			}
+
+			evs, err := parseTrades(message, canonical)
+			if err != nil {
+				log.Printf("kraken trade parse error: %v", err)
+				return true
+			}
+			for _, ev := range evs {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		})
+	}()
+
+	return ch, nil
+}
+
+// parseTrades decodes [channelID, [[price, volume, time, side, type, misc], ...], "trade", pair].
+func parseTrades(message []byte, canonical map[string]string) ([]types.PriceEvent, error) {
+	var frame []json.RawMessage
+	if err := json.Unmarshal(message, &frame); err != nil {
+		return nil, err
+	}
+	if len(frame) < 4 {
+		return nil, fmt.Errorf("short frame")
+	}
+
+	var channel, pair string
+	if err := json.Unmarshal(frame[len(frame)-2], &channel); err != nil || channel != "trade" {
+		return nil, nil
+	}
+	if err := json.Unmarshal(frame[len(frame)-1], &pair); err != nil {
+		return nil, err
+	}
+	sym, ok := canonical[pair]
+	if !ok {
+		return nil, nil
+	}
+
+	var rows [][]string
+	if err := json.Unmarshal(frame[1], &rows); err != nil {
+		return nil, err
+	}
+
+	out := make([]types.PriceEvent, 0, len(rows))
+	for _, r := range rows {
+		if len(r) < 4 {
+			continue
+		}
+		price, err := strconv.ParseFloat(r[0], 64)
+		if err != nil {
+			return out, fmt.Errorf("price: %w", err)
+		}
+		vol, err := strconv.ParseFloat(r[1], 64)
+		if err != nil {
+			return out, fmt.Errorf("volume: %w", err)
+		}
+		ts, err := strconv.ParseFloat(r[2], 64)
+		if err != nil {
+			return out, fmt.Errorf("time: %w", err)
+		}
+		sec, frac := math.Modf(ts)
+
+		out = append(out, types.PriceEvent{
+			Symbol:    sym,
+			Price:     price,
+			Quantity:  vol,
+			Timestamp: time.Unix(int64(sec), int64(math.Round(frac*1e6))*1000),
+			Source:    "kraken",
+			// side is the taker side: "s" means a seller hit a resting bid.
+			BuyerIsMaker: r[3] == "s",
+		})
+	}
+	return out, nil
+}
+
+// Candles aggregates trades locally.
+func (s *Source) Candles(ctx context.Context, symbol, interval string) (<-chan candle.Candle, error) {
+	return market.CandlesFromTrades(ctx, s, symbol, interval)
+}
@@ -0,0 +1,50 @@
+package kraken
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPairMapping(t *testing.T) {
+	cases := []struct {
+		symbol string
+		pair   string
+	}{
+		{"BTCUSDT", "XBT/USD"},
+		{"ETHUSDT", "ETH/USD"},
+		{"ETHBTC", "ETH/XBT"},
+		{"DOGEUSDT", "XDG/USD"},
+		{"SOLEUR", "SOL/EUR"},
+	}
+	for _, tc := range cases {
+		p, err := Pair(tc.symbol)
+		if err != nil || p != tc.pair {
+			t.Errorf("Pair(%q) = %q, %v; want %q", tc.symbol, p, err, tc.pair)
+		}
+		s, err := Symbol(tc.pair)
+		if err != nil || s != tc.symbol {
+			t.Errorf("Symbol(%q) = %q, %v; want %q", tc.pair, s, err, tc.symbol)
+		}
+	}
+}
+
+func TestParseTrades(t *testing.T) {
+	msg := []byte(`[337,[["5541.20000","0.15850568","1534614057.321597","s","l",""],["6060.00000","0.02455000","1534614057.324998","b","l",""]],"trade","XBT/USD"]`)
+	evs, err := parseTrades(msg, map[string]string{"XBT/USD": "BTCUSDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("got %d trades, want 2", len(evs))
+	}
+	ev := evs[0]
+	if ev.Symbol != "BTCUSDT" || ev.Source != "kraken" || ev.Price != 5541.2 || ev.Quantity != 0.15850568 || !ev.BuyerIsMaker {
+		t.Errorf("unexpected trade: %+v", ev)
+	}
+	if want := time.Unix(1534614057, 321597000); !ev.Timestamp.Equal(want) {
+		t.Errorf("timestamp = %v, want %v", ev.Timestamp, want)
+	}
+	if evs[1].BuyerIsMaker {
+		t.Errorf("buy-side taker reported as maker")
+	}
+}
@@ -0,0 +1,107 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
+)
+
+// MarketSource is a venue that streams trades and candles. Symbols are always
+// in the canonical form used across the engine (e.g. BTCUSDT); adapters
+// translate to and from venue-specific names and set PriceEvent.Source.
+type MarketSource interface {
+	Name() string
+	Trades(ctx context.Context, symbols []string) (<-chan types.PriceEvent, error)
+	Candles(ctx context.Context, symbol, interval string) (<-chan candle.Candle, error)
+}
+
+// quoteAssets are matched longest first when splitting a canonical symbol.
+var quoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "USD", "EUR", "GBP", "BTC", "ETH", "BNB"}
+
+// Instrument is a base/quote pair.
+type Instrument struct {
+	Base  string
+	Quote string
+}
+
+// ParseSymbol splits a canonical symbol such as BTCUSDT into base and quote.
+func ParseSymbol(symbol string) (Instrument, error) {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+	for _, q := range quoteAssets {
+		if len(s) > len(q) && strings.HasSuffix(s, q) {
+			return Instrument{Base: strings.TrimSuffix(s, q), Quote: q}, nil
+		}
+	}
+	return Instrument{}, fmt.Errorf("unknown quote asset in %q", symbol)
+}
+
+// Symbol returns the canonical symbol.
+func (i Instrument) Symbol() string {
+	return i.Base + i.Quote
+}
+
+// ParseInterval converts a kline interval such as 1s, 5m, 4h, 1d or 1w to a duration.
+func ParseInterval(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("invalid interval %q", interval)
+	}
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid interval %q", interval)
+	}
+
+	var unit time.Duration
+	switch interval[len(interval)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid interval %q", interval)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// CandlesFromTrades builds candles for symbol by aggregating the trade stream
+// of src. It is the Candles implementation for venues without a kline feed.
+func CandlesFromTrades(ctx context.Context, src MarketSource, symbol, interval string) (<-chan candle.Candle, error) {
+	d, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	trades, err := src.Trades(ctx, []string{symbol})
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan candle.Candle, 100)
+	go func() {
+		defer close(ch)
+
+		agg := candle.NewAggregator(d)
+		for ev := range trades {
+			c, ok := agg.Push(ev)
+			if !ok {
+				continue
+			}
+			select {
+			case ch <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
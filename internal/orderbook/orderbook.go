@@ -0,0 +1,225 @@
+package orderbook
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type Level struct {
+	Price float64 `json:"price"`
+	Qty   float64 `json:"qty"`
+}
+
+// Snapshot is a consistent top-of-book view returned by Book.Top.
+type Snapshot struct {
+	Symbol       string    `json:"symbol"`
+	Bids         []Level   `json:"bids"`
+	Asks         []Level   `json:"asks"`
+	Mid          float64   `json:"mid"`
+	Spread       float64   `json:"spread"`
+	LastUpdateID int64     `json:"lastUpdateId"`
+	Synced       bool      `json:"synced"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Book is a thread-safe price level book for one symbol. Levels with zero
+// quantity are removed.
+type Book struct {
+	mu sync.RWMutex
+
+	symbol       string
+	bids         map[float64]float64
+	asks         map[float64]float64
+	lastUpdateID int64
+	synced       bool
+	updatedAt    time.Time
+}
+
+func New(symbol string) *Book {
+	return &Book{
+		symbol: symbol,
+		bids:   make(map[float64]float64),
+		asks:   make(map[float64]float64),
+	}
+}
+
+func (b *Book) Symbol() string {
+	return b.symbol
+}
+
+// Reset replaces the whole book with a snapshot. The book stays out of sync
+// until MarkSynced, so diffs buffered since the snapshot can be replayed first.
+func (b *Book) Reset(bids, asks []Level, lastUpdateID int64, t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bids = make(map[float64]float64, len(bids))
+	b.asks = make(map[float64]float64, len(asks))
+	setLevels(b.bids, bids)
+	setLevels(b.asks, asks)
+	b.lastUpdateID = lastUpdateID
+	b.synced = false
+	b.updatedAt = t
+}
+
+// MarkSynced marks the book as in sync with the stream.
+func (b *Book) MarkSynced() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.synced = true
+}
+
+// Apply merges a diff into the book and advances the update ID.
+func (b *Book) Apply(bids, asks []Level, updateID int64, t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	setLevels(b.bids, bids)
+	setLevels(b.asks, asks)
+	b.lastUpdateID = updateID
+	b.updatedAt = t
+}
+
+// Invalidate marks the book as out of sync until the next Reset.
+func (b *Book) Invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.synced = false
+}
+
+func (b *Book) Synced() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.synced
+}
+
+func (b *Book) LastUpdateID() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.lastUpdateID
+}
+
+// BestBid returns the highest bid level.
+func (b *Book) BestBid() (Level, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return best(b.bids, true)
+}
+
+// BestAsk returns the lowest ask level.
+func (b *Book) BestAsk() (Level, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return best(b.asks, false)
+}
+
+func (b *Book) Mid() (float64, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	bid, okB := best(b.bids, true)
+	ask, okA := best(b.asks, false)
+	if !okB || !okA {
+		return 0, false
+	}
+	return (bid.Price + ask.Price) / 2, true
+}
+
+func (b *Book) Spread() (float64, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	bid, okB := best(b.bids, true)
+	ask, okA := best(b.asks, false)
+	if !okB || !okA {
+		return 0, false
+	}
+	return ask.Price - bid.Price, true
+}
+
+// Top returns the best n levels per side (all levels when n <= 0).
+func (b *Book) Top(n int) Snapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	s := Snapshot{
+		Symbol:       b.symbol,
+		Bids:         sorted(b.bids, n, true),
+		Asks:         sorted(b.asks, n, false),
+		LastUpdateID: b.lastUpdateID,
+		Synced:       b.synced,
+		Timestamp:    b.updatedAt,
+	}
+	if len(s.Bids) > 0 && len(s.Asks) > 0 {
+		s.Mid = (s.Bids[0].Price + s.Asks[0].Price) / 2
+		s.Spread = s.Asks[0].Price - s.Bids[0].Price
+	}
+	return s
+}
+
+func setLevels(side map[float64]float64, levels []Level) {
+	for _, l := range levels {
+		if l.Qty == 0 {
+			delete(side, l.Price)
+			continue
+		}
+		side[l.Price] = l.Qty
+	}
+}
+
+func best(side map[float64]float64, desc bool) (Level, bool) {
+	var out Level
+	found := false
+	for p, q := range side {
+		if !found || (desc && p > out.Price) || (!desc && p < out.Price) {
+			out = Level{Price: p, Qty: q}
+			found = true
+		}
+	}
+	return out, found
+}
+
+func sorted(side map[float64]float64, n int, desc bool) []Level {
+	out := make([]Level, 0, len(side))
+	for p, q := range side {
+		out = append(out, Level{Price: p, Qty: q})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if desc {
+			return out[i].Price > out[j].Price
+		}
+		return out[i].Price < out[j].Price
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Books is a thread-safe registry of books keyed by symbol.
+type Books struct {
+	mu    sync.RWMutex
+	books map[string]*Book
+}
+
+func NewBooks() *Books {
+	return &Books{books: make(map[string]*Book)}
+}
+
+// GetOrCreate returns the book for symbol, creating an empty one if needed.
+func (r *Books) GetOrCreate(symbol string) *Book {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.books[symbol]
+	if !ok {
+		b = New(symbol)
+		r.books[symbol] = b
+	}
+	return b
+}
+
+func (r *Books) Get(symbol string) (*Book, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	b, ok := r.books[symbol]
+	return b, ok
+}
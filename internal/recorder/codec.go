@@ -0,0 +1,290 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+// Segment file layout:
+//
+//	magic "RMETICK1"
+//	block*: u32 payload length | u32 CRC-32C of payload | payload
+//
+// Every block is self-contained, so a block whose payload fails its checksum
+// is skipped and the blocks after it still decode. A damaged length field
+// cannot be resynced past: the reader then fails with ErrCorrupt or skips the
+// misaligned rest of the segment. A payload holds uvarint event count and varint base
+// timestamp (unix ns), followed by one record per event:
+//
+//	uvarint stream index; a new index is followed by source and symbol strings
+//	varint  timestamp delta to the previous record (ns)
+//	byte    flags: 1 = buyer is maker, 2 = trade IDs present
+//	xor     price, XOR-ed with the stream's previous price
+//	xor     quantity, XOR-ed with the stream's previous quantity
+//	IDs     varint aggregate ID delta, varint first ID minus previous last
+//	        ID minus one, uvarint last ID minus first ID (when flag 2 is set)
+//
+// An XOR value is a header byte (leading zero bytes << 4 | trailing zero
+// bytes) followed by the remaining significant bytes, big-endian.
+const magic = "RMETICK1"
+
+const (
+	flagBuyerIsMaker = 1 << 0
+	flagTradeIDs     = 1 << 1
+)
+
+var (
+	ErrBadMagic = errors.New("recorder: not a segment file")
+	ErrCorrupt  = errors.New("recorder: corrupt block")
+)
+
+type streamKey struct {
+	source string
+	symbol string
+}
+
+type streamState struct {
+	key       streamKey
+	price     uint64
+	qty       uint64
+	aggID     int64
+	lastTrade int64
+}
+
+// blockEncoder accumulates records for one block.
+type blockEncoder struct {
+	buf     []byte
+	count   int
+	baseTs  int64
+	prevTs  int64
+	index   map[streamKey]int
+	streams []streamState
+}
+
+func newBlockEncoder() *blockEncoder {
+	return &blockEncoder{index: make(map[streamKey]int)}
+}
+
+func (e *blockEncoder) reset() {
+	e.buf = e.buf[:0]
+	e.count = 0
+	e.streams = e.streams[:0]
+	clear(e.index)
+}
+
+func (e *blockEncoder) add(ev types.PriceEvent) {
+	ts := ev.Timestamp.UnixNano()
+	if e.count == 0 {
+		e.baseTs = ts
+		e.prevTs = ts
+	}
+
+	key := streamKey{source: ev.Source, symbol: ev.Symbol}
+	idx, ok := e.index[key]
+	if !ok {
+		idx = len(e.streams)
+		e.index[key] = idx
+		e.streams = append(e.streams, streamState{key: key})
+	}
+	st := &e.streams[idx]
+
+	e.buf = binary.AppendUvarint(e.buf, uint64(idx))
+	if !ok {
+		e.buf = appendString(e.buf, key.source)
+		e.buf = appendString(e.buf, key.symbol)
+	}
+
+	e.buf = binary.AppendVarint(e.buf, ts-e.prevTs)
+	e.prevTs = ts
+
+	var flags byte
+	if ev.BuyerIsMaker {
+		flags |= flagBuyerIsMaker
+	}
+	hasIDs := ev.AggTradeID != 0 || ev.FirstTradeID != 0 || ev.LastTradeID != 0
+	if hasIDs {
+		flags |= flagTradeIDs
+	}
+	e.buf = append(e.buf, flags)
+
+	p := math.Float64bits(ev.Price)
+	q := math.Float64bits(ev.Quantity)
+	e.buf = appendXOR(e.buf, p^st.price)
+	e.buf = appendXOR(e.buf, q^st.qty)
+	st.price, st.qty = p, q
+
+	if hasIDs {
+		e.buf = binary.AppendVarint(e.buf, ev.AggTradeID-st.aggID)
+		e.buf = binary.AppendVarint(e.buf, ev.FirstTradeID-st.lastTrade-1)
+		e.buf = binary.AppendUvarint(e.buf, uint64(ev.LastTradeID-ev.FirstTradeID))
+		st.aggID, st.lastTrade = ev.AggTradeID, ev.LastTradeID
+	}
+
+	e.count++
+}
+
+// payload returns the encoded block payload.
+func (e *blockEncoder) payload() []byte {
+	out := make([]byte, 0, len(e.buf)+2*binary.MaxVarintLen64)
+	out = binary.AppendUvarint(out, uint64(e.count))
+	out = binary.AppendVarint(out, e.baseTs)
+	return append(out, e.buf...)
+}
+
+// decodeBlock decodes a block payload into events.
+func decodeBlock(p []byte, out []types.PriceEvent) ([]types.PriceEvent, error) {
+	d := decoder{b: p}
+	n := d.uvarint()
+	ts := d.varint()
+	if d.err != nil || n > uint64(len(p)) {
+		return out, ErrCorrupt
+	}
+
+	var streams []streamState
+	for i := uint64(0); i < n; i++ {
+		idx := d.uvarint()
+		if idx == uint64(len(streams)) {
+			src := d.string()
+			sym := d.string()
+			streams = append(streams, streamState{key: streamKey{source: src, symbol: sym}})
+		} else if idx > uint64(len(streams)) {
+			return out, ErrCorrupt
+		}
+		st := &streams[idx]
+
+		ts += d.varint()
+		flags := d.byte()
+		st.price ^= d.xor()
+		st.qty ^= d.xor()
+
+		ev := types.PriceEvent{
+			Symbol:       st.key.symbol,
+			Source:       st.key.source,
+			Price:        math.Float64frombits(st.price),
+			Quantity:     math.Float64frombits(st.qty),
+			Timestamp:    time.Unix(0, ts),
+			BuyerIsMaker: flags&flagBuyerIsMaker != 0,
+		}
+		if flags&flagTradeIDs != 0 {
+			st.aggID += d.varint()
+			first := st.lastTrade + 1 + d.varint()
+			last := first + int64(d.uvarint())
+			st.lastTrade = last
+			ev.AggTradeID, ev.FirstTradeID, ev.LastTradeID = st.aggID, first, last
+		}
+		if d.err != nil {
+			return out, ErrCorrupt
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendXOR(b []byte, x uint64) []byte {
+	if x == 0 {
+		return append(b, 8<<4)
+	}
+	lead := bits.LeadingZeros64(x) / 8
+	trail := bits.TrailingZeros64(x) / 8
+	b = append(b, byte(lead<<4|trail))
+	for i := 7 - lead; i >= trail; i-- {
+		b = append(b, byte(x>>(8*i)))
+	}
+	return b
+}
+
+// decoder reads primitives from b and records the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = ErrCorrupt
+	}
+	d.b = nil
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if len(d.b) < 1 {
+		d.fail()
+		return 0
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		d.fail()
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *decoder) xor() uint64 {
+	h := d.byte()
+	lead, trail := int(h>>4), int(h&0x0f)
+	if lead == 8 {
+		return 0
+	}
+	if lead+trail > 7 {
+		d.fail()
+		return 0
+	}
+	var x uint64
+	for i := 7 - lead; i >= trail; i-- {
+		x |= uint64(d.byte()) << (8 * i)
+	}
+	return x
+}
+
+func blockHeader(payload []byte, crc uint32) []byte {
+	h := make([]byte, 8)
+	binary.LittleEndian.PutUint32(h[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(h[4:8], crc)
+	return h
+}
+
+func checkMagic(b []byte) error {
+	if string(b) != magic {
+		return fmt.Errorf("%w: %q", ErrBadMagic, b)
+	}
+	return nil
+}
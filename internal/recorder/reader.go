@@ -0,0 +1,218 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+// maxBlockBytes guards against allocating huge buffers for corrupt lengths.
+const maxBlockBytes = 64 << 20
+
+// SegmentReader streams events from one segment. A block cut short at the end
+// of the segment, as left behind by a crash, ends the stream like io.EOF;
+// blocks failing their checksum are skipped and counted.
+type SegmentReader struct {
+	r       *bufio.Reader
+	events  []types.PriceEvent
+	pos     int
+	skipped int
+}
+
+func NewSegmentReader(r io.Reader) (*SegmentReader, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadMagic, err)
+	}
+	if err := checkMagic(m); err != nil {
+		return nil, err
+	}
+	return &SegmentReader{r: br}, nil
+}
+
+// Next returns the next event or io.EOF.
+func (s *SegmentReader) Next() (types.PriceEvent, error) {
+	for s.pos >= len(s.events) {
+		if err := s.readBlock(); err != nil {
+			return types.PriceEvent{}, err
+		}
+	}
+	ev := s.events[s.pos]
+	s.pos++
+	return ev, nil
+}
+
+// Skipped returns the number of blocks dropped for a checksum mismatch.
+func (s *SegmentReader) Skipped() int { return s.skipped }
+
+func (s *SegmentReader) readBlock() error {
+	var h [8]byte
+	if _, err := io.ReadFull(s.r, h[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return io.EOF
+		}
+		return err
+	}
+	n := binary.LittleEndian.Uint32(h[0:4])
+	crc := binary.LittleEndian.Uint32(h[4:8])
+	if n > maxBlockBytes {
+		return ErrCorrupt
+	}
+
+	p := make([]byte, n)
+	if _, err := io.ReadFull(s.r, p); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return err
+	}
+	if crc32.Checksum(p, castagnoli) != crc {
+		s.skipped++
+		s.events, s.pos = s.events[:0], 0
+		return nil
+	}
+
+	events, err := decodeBlock(p, s.events[:0])
+	if err != nil {
+		return err
+	}
+	s.events, s.pos = events, 0
+	return nil
+}
+
+// Reader streams the events of all segments in a directory in the order they
+// were recorded, restricted to from <= Timestamp < to (zero bounds are open).
+// That is arrival order: late events are not re-sorted, and since they are
+// written to the newest segment, those in a segment starting at or after to
+// are not returned.
+type Reader struct {
+	files    []string
+	from, to time.Time
+
+	idx     int
+	f       *os.File
+	seg     *SegmentReader
+	skipped int
+}
+
+// OpenDir opens the segments in dir that may hold events in [from, to).
+func OpenDir(dir string, from, to time.Time) (*Reader, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	// A segment can only hold events up to the start of the next window.
+	var files []string
+	for i, name := range names {
+		start, ok := segmentStart(name)
+		if !ok {
+			continue
+		}
+		if !to.IsZero() && !start.Before(to) {
+			break
+		}
+		if !from.IsZero() && i+1 < len(names) {
+			if next, ok := segmentStart(names[i+1]); ok && !next.After(from) && !next.Equal(start) {
+				continue
+			}
+		}
+		files = append(files, name)
+	}
+
+	return &Reader{files: files, from: from, to: to}, nil
+}
+
+// Files returns the segment files the reader will visit.
+func (r *Reader) Files() []string {
+	return append([]string(nil), r.files...)
+}
+
+// Next returns the next event in range or io.EOF.
+func (r *Reader) Next() (types.PriceEvent, error) {
+	for {
+		if r.seg == nil {
+			if r.idx >= len(r.files) {
+				return types.PriceEvent{}, io.EOF
+			}
+			if err := r.open(r.files[r.idx]); err != nil {
+				return types.PriceEvent{}, err
+			}
+			r.idx++
+		}
+
+		ev, err := r.seg.Next()
+		if err == io.EOF {
+			if n := r.seg.Skipped(); n > 0 {
+				log.Printf("recorder: %s: skipped %d corrupt block(s)", r.files[r.idx-1], n)
+			}
+			r.skipped += r.seg.Skipped()
+			r.closeFile()
+			continue
+		}
+		if err != nil {
+			return types.PriceEvent{}, fmt.Errorf("%s: %w", r.files[r.idx-1], err)
+		}
+		if !r.from.IsZero() && ev.Timestamp.Before(r.from) {
+			continue
+		}
+		if !r.to.IsZero() && !ev.Timestamp.Before(r.to) {
+			continue
+		}
+		return ev, nil
+	}
+}
+
+// Skipped returns the number of corrupt blocks skipped in the segments read
+// to the end so far.
+func (r *Reader) Skipped() int { return r.skipped }
+
+func (r *Reader) Close() error {
+	r.closeFile()
+	r.idx = len(r.files)
+	return nil
+}
+
+func (r *Reader) open(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	seg, err := NewSegmentReader(f)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	r.f, r.seg = f, seg
+	return nil
+}
+
+func (r *Reader) closeFile() {
+	if r.f != nil {
+		_ = r.f.Close()
+	}
+	r.f, r.seg = nil, nil
+}
+
+func segmentStart(name string) (time.Time, bool) {
+	base := filepath.Base(name)
+	stamp, _, ok := strings.Cut(base, "-")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(segmentTimeLayout, stamp)
+	return t, err == nil
+}
@@ -0,0 +1,212 @@
+package recorder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+var ErrClosed = errors.New("recorder: closed")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type config struct {
+	segmentDuration time.Duration
+	maxSegmentBytes int64
+	blockEvents     int
+	flushInterval   time.Duration
+}
+
+type Option func(*config)
+
+// WithSegmentDuration sets the UTC time window covered by one segment (default 1h).
+func WithSegmentDuration(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.segmentDuration = d
+		}
+	}
+}
+
+// WithMaxSegmentBytes starts a new segment within the same window once a
+// segment reaches n bytes (default 256 MiB).
+func WithMaxSegmentBytes(n int64) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxSegmentBytes = n
+		}
+	}
+}
+
+// WithBlockEvents sets how many events are packed into one checksummed block (default 4096).
+func WithBlockEvents(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.blockEvents = n
+		}
+	}
+}
+
+// WithFlushInterval bounds how long events may sit in an unwritten block (default 1s).
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.flushInterval = d
+		}
+	}
+}
+
+// Recorder appends PriceEvents to time-partitioned segment files in dir.
+// Segments are named <start>-<seq>.seg, e.g. 20260208T100000Z-000.seg, so
+// that lexical order is time order. It is safe for concurrent use.
+type Recorder struct {
+	dir string
+	cfg config
+
+	mu        sync.Mutex
+	f         *os.File
+	w         *bufio.Writer
+	segStart  time.Time
+	segBytes  int64
+	blk       *blockEncoder
+	lastFlush time.Time
+	closed    bool
+}
+
+func New(dir string, opts ...Option) (*Recorder, error) {
+	cfg := config{
+		segmentDuration: time.Hour,
+		maxSegmentBytes: 256 << 20,
+		blockEvents:     4096,
+		flushInterval:   time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, cfg: cfg, blk: newBlockEncoder()}, nil
+}
+
+func (r *Recorder) Write(ev types.PriceEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+
+	window := ev.Timestamp.UTC().Truncate(r.cfg.segmentDuration)
+	if r.f == nil || window.After(r.segStart) || r.segBytes >= r.cfg.maxSegmentBytes {
+		if err := r.rotate(window); err != nil {
+			return err
+		}
+	}
+
+	r.blk.add(ev)
+	if r.blk.count >= r.cfg.blockEvents || time.Since(r.lastFlush) >= r.cfg.flushInterval {
+		return r.flushLocked()
+	}
+	return nil
+}
+
+// Flush writes the pending block and fsyncs the segment file, so that the
+// events written so far survive a machine crash.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if err := r.flushLocked(); err != nil {
+		return err
+	}
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Sync()
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.closeSegment()
+}
+
+func (r *Recorder) flushLocked() error {
+	r.lastFlush = time.Now()
+	if r.w == nil {
+		return nil
+	}
+	if r.blk.count > 0 {
+		p := r.blk.payload()
+		if _, err := r.w.Write(blockHeader(p, crc32.Checksum(p, castagnoli))); err != nil {
+			return err
+		}
+		if _, err := r.w.Write(p); err != nil {
+			return err
+		}
+		r.segBytes += int64(8 + len(p))
+		r.blk.reset()
+	}
+	return r.w.Flush()
+}
+
+func (r *Recorder) closeSegment() error {
+	if r.f == nil {
+		return nil
+	}
+	err := r.flushLocked()
+	if err == nil {
+		err = r.f.Sync()
+	}
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	r.f, r.w = nil, nil
+	return err
+}
+
+// rotate finishes the current segment and opens the next free one for window.
+func (r *Recorder) rotate(window time.Time) error {
+	if err := r.closeSegment(); err != nil {
+		return err
+	}
+	if window.Before(r.segStart) {
+		// Late events stay in the newest window rather than reopening an old one.
+		window = r.segStart
+	}
+
+	for seq := 0; ; seq++ {
+		name := filepath.Join(r.dir, fmt.Sprintf("%s-%03d.seg", window.Format(segmentTimeLayout), seq))
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		r.f = f
+		r.w = bufio.NewWriterSize(f, 64<<10)
+		r.segStart = window
+		r.segBytes = int64(len(magic))
+		r.lastFlush = time.Now()
+		_, err = r.w.WriteString(magic)
+		return err
+	}
+}
+
+const segmentTimeLayout = "20060102T150405Z"
@@ -0,0 +1,253 @@
+package recorder
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+func testEvents(start time.Time, n int) []types.PriceEvent {
+	var out []types.PriceEvent
+	for i := 0; i < n; i++ {
+		ev := types.PriceEvent{
+			Symbol:    []string{"BTCUSDT", "ETHUSDT"}[i%2],
+			Source:    []string{"binance", "kraken"}[i%3%2],
+			Price:     100 + float64(i%7)*0.01,
+			Quantity:  0.5 + float64(i%5),
+			Timestamp: start.Add(time.Duration(i) * 250 * time.Millisecond),
+		}
+		if ev.Source == "binance" {
+			ev.AggTradeID = int64(1000 + i)
+			ev.FirstTradeID = int64(5000 + 3*i)
+			ev.LastTradeID = ev.FirstTradeID + int64(i%3)
+			ev.BuyerIsMaker = i%2 == 0
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+func readAll(t *testing.T, r interface {
+	Next() (types.PriceEvent, error)
+}) []types.PriceEvent {
+	t.Helper()
+	var out []types.PriceEvent
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		out = append(out, ev)
+	}
+}
+
+func assertEqual(t *testing.T, got, want []types.PriceEvent) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if !g.Timestamp.Equal(w.Timestamp) {
+			t.Fatalf("event %d timestamp = %v, want %v", i, g.Timestamp, w.Timestamp)
+		}
+		g.Timestamp, w.Timestamp = time.Time{}, time.Time{}
+		if g != w {
+			t.Fatalf("event %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	events := testEvents(start, 1000)
+
+	rec, err := New(dir, WithBlockEvents(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range events {
+		if err := rec.Write(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Write(events[0]); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Write after Close = %v, want ErrClosed", err)
+	}
+
+	r, err := OpenDir(dir, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	assertEqual(t, readAll(t, r), events)
+}
+
+func TestRotationAndRange(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	events := testEvents(start, 4*60*4) // four minutes of ticks
+
+	rec, err := New(dir, WithSegmentDuration(time.Minute), WithBlockEvents(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range events {
+		if err := rec.Write(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if len(names) != 4 {
+		t.Fatalf("got %d segments, want 4: %v", len(names), names)
+	}
+	if base := filepath.Base(names[0]); base != "20260208T100000Z-000.seg" {
+		t.Fatalf("first segment = %s", base)
+	}
+
+	from := start.Add(90 * time.Second)
+	to := start.Add(150 * time.Second)
+	r, err := OpenDir(dir, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if n := len(r.Files()); n != 2 {
+		t.Fatalf("range opened %d segments, want 2", n)
+	}
+
+	var want []types.PriceEvent
+	for _, ev := range events {
+		if !ev.Timestamp.Before(from) && ev.Timestamp.Before(to) {
+			want = append(want, ev)
+		}
+	}
+	assertEqual(t, readAll(t, r), want)
+}
+
+func TestReopenDoesNotOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	events := testEvents(start, 20)
+
+	for _, part := range [][]types.PriceEvent{events[:10], events[10:]} {
+		rec, err := New(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ev := range part {
+			if err := rec.Write(ev); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := rec.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := OpenDir(dir, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if n := len(r.Files()); n != 2 {
+		t.Fatalf("got %d segments, want 2", n)
+	}
+	assertEqual(t, readAll(t, r), events)
+}
+
+func TestCorruption(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	events := testEvents(start, 100)
+
+	rec, err := New(dir, WithBlockEvents(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range events {
+		if err := rec.Write(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	orig, err := os.ReadFile(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("truncated tail", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "*.seg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.Write(orig[:len(orig)-5]); err != nil {
+			t.Fatal(err)
+		}
+		f.Seek(0, io.SeekStart)
+
+		sr, err := NewSegmentReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, readAll(t, sr), events[:90])
+	})
+
+	t.Run("flipped bit", func(t *testing.T) {
+		b := append([]byte(nil), orig...)
+		b[len(magic)+8+3] ^= 0x10
+
+		f, err := os.CreateTemp(t.TempDir(), "*.seg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		f.Write(b)
+		f.Seek(0, io.SeekStart)
+
+		sr, err := NewSegmentReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The damaged first block is skipped, the rest still reads.
+		assertEqual(t, readAll(t, sr), events[10:])
+		if sr.Skipped() != 1 {
+			t.Fatalf("Skipped = %d, want 1", sr.Skipped())
+		}
+	})
+
+	t.Run("bad magic", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "*.seg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		f.WriteString("NOTASEGMENT")
+		f.Seek(0, io.SeekStart)
+
+		if _, err := NewSegmentReader(f); !errors.Is(err, ErrBadMagic) {
+			t.Fatalf("NewSegmentReader = %v, want ErrBadMagic", err)
+		}
+	})
+}
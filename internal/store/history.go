@@ -0,0 +1,129 @@
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+// ring is a fixed-capacity buffer of elements in time order that overwrites
+// its oldest element when full. Late elements are inserted at their place;
+// those older than everything kept in a full ring are dropped.
+type ring[T any] struct {
+	buf   []T
+	head  int // index of the oldest element
+	size  int
+	ts    func(T) time.Time
+	limit int
+}
+
+func newRing[T any](limit int, ts func(T) time.Time) *ring[T] {
+	return &ring[T]{limit: limit, ts: ts}
+}
+
+func (r *ring[T]) at(i int) T {
+	return r.buf[(r.head+i)%len(r.buf)]
+}
+
+func (r *ring[T]) push(v T, maxAge time.Duration) {
+	if r.limit <= 0 {
+		return
+	}
+	if len(r.buf) < r.limit && r.size == len(r.buf) {
+		// Grow lazily so idle symbols stay cheap.
+		n := len(r.buf) * 2
+		if n == 0 {
+			n = 64
+		}
+		if n > r.limit {
+			n = r.limit
+		}
+		buf := make([]T, n)
+		for i := 0; i < r.size; i++ {
+			buf[i] = r.at(i)
+		}
+		r.buf = buf
+		r.head = 0
+	}
+
+	t := r.ts(v)
+	switch {
+	case r.size == 0 || !t.Before(r.ts(r.at(r.size-1))):
+		if r.size == len(r.buf) {
+			r.buf[r.head] = v
+			r.head = (r.head + 1) % len(r.buf)
+		} else {
+			r.buf[(r.head+r.size)%len(r.buf)] = v
+			r.size++
+		}
+	default:
+		i := sort.Search(r.size, func(i int) bool { return r.ts(r.at(i)).After(t) })
+		if r.size == len(r.buf) {
+			if i == 0 {
+				return
+			}
+			r.head = (r.head + 1) % len(r.buf)
+			r.size--
+			i--
+		}
+		r.size++
+		for j := r.size - 1; j > i; j-- {
+			r.set(j, r.at(j-1))
+		}
+		r.set(i, v)
+	}
+
+	if maxAge > 0 {
+		cut := r.ts(r.at(r.size - 1)).Add(-maxAge)
+		var zero T
+		for r.size > 0 && r.ts(r.at(0)).Before(cut) {
+			r.buf[r.head] = zero
+			r.head = (r.head + 1) % len(r.buf)
+			r.size--
+		}
+	}
+}
+
+func (r *ring[T]) set(i int, v T) {
+	r.buf[(r.head+i)%len(r.buf)] = v
+}
+
+// replace overwrites the element with the same timestamp as v and reports
+// whether there was one.
+func (r *ring[T]) replace(v T) bool {
+	t := r.ts(v)
+	i := sort.Search(r.size, func(i int) bool { return !r.ts(r.at(i)).Before(t) })
+	if i == r.size || !r.ts(r.at(i)).Equal(t) {
+		return false
+	}
+	r.set(i, v)
+	return true
+}
+
+// last returns up to n newest elements, oldest first.
+func (r *ring[T]) last(n int) []T {
+	if n > r.size {
+		n = r.size
+	}
+	if n <= 0 {
+		return nil
+	}
+	out := make([]T, n)
+	for i := 0; i < n; i++ {
+		out[i] = r.at(r.size - n + i)
+	}
+	return out
+}
+
+// between returns the elements with from <= ts <= to, oldest first.
+func (r *ring[T]) between(from, to time.Time) []T {
+	lo := sort.Search(r.size, func(i int) bool { return !r.ts(r.at(i)).Before(from) })
+	hi := sort.Search(r.size, func(i int) bool { return r.ts(r.at(i)).After(to) })
+	if hi <= lo {
+		return nil
+	}
+	out := make([]T, hi-lo)
+	for i := lo; i < hi; i++ {
+		out[i-lo] = r.at(i)
+	}
+	return out
+}
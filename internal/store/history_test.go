@@ -0,0 +1,198 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
+)
+
+var t0 = time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+
+func tick(i int) types.PriceEvent {
+	return types.PriceEvent{Symbol: "BTCUSDT", Source: "binance", Price: float64(i), Timestamp: t0.Add(time.Duration(i) * time.Second)}
+}
+
+func TestHistoryRangeAndLast(t *testing.T) {
+	s := NewPriceStore(WithRetention(Retention{Ticks: 100, Candles: 10}))
+	for i := 0; i < 250; i++ {
+		s.Update(tick(i))
+	}
+
+	last := s.Last("binance", "BTCUSDT", 3)
+	if len(last) != 3 || last[0].Price != 247 || last[2].Price != 249 {
+		t.Errorf("unexpected Last: %+v", last)
+	}
+	if got := s.Last("binance", "BTCUSDT", 1000); len(got) != 100 || got[0].Price != 150 {
+		t.Errorf("retention not applied: len=%d first=%v", len(got), got[0].Price)
+	}
+
+	r := s.Range("binance", "BTCUSDT", t0.Add(140*time.Second), t0.Add(160*time.Second))
+	if len(r) != 11 || r[0].Price != 150 || r[10].Price != 160 {
+		t.Errorf("unexpected Range: len=%d", len(r))
+	}
+	if got := s.Range("binance", "ETHUSDT", t0, t0.Add(time.Hour)); got != nil {
+		t.Errorf("unknown symbol returned %d ticks", len(got))
+	}
+
+	for i := 0; i < 12; i++ {
+		start := t0.Add(time.Duration(i) * time.Minute)
+		s.AddCandle(candle.Candle{Symbol: "BTCUSDT", Source: "binance", Start: start, End: start.Add(time.Minute), Close: float64(i)})
+	}
+	cs := s.LastCandles("binance", "BTCUSDT", 20)
+	if len(cs) != 10 || cs[0].Close != 2 {
+		t.Errorf("unexpected candles: len=%d", len(cs))
+	}
+	if got := s.CandleRange("binance", "BTCUSDT", t0.Add(5*time.Minute), t0.Add(6*time.Minute)); len(got) != 2 {
+		t.Errorf("unexpected CandleRange len=%d", len(got))
+	}
+}
+
+func TestHistoryMaxAge(t *testing.T) {
+	s := NewPriceStore(WithRetention(Retention{Ticks: 1000, MaxAge: time.Minute}))
+	for i := 0; i < 300; i++ {
+		s.Update(tick(i))
+	}
+	got := s.Last("binance", "BTCUSDT", 1000)
+	if len(got) != 61 || got[0].Price != 239 {
+		t.Errorf("max age not applied: len=%d first=%v", len(got), got[0].Price)
+	}
+}
+
+func TestHistoryVenuesAndLateEntries(t *testing.T) {
+	s := NewPriceStore(WithRetention(Retention{Ticks: 5, Candles: 10}))
+
+	// Two venues quoting the same symbol keep separate histories.
+	for i := 0; i < 4; i++ {
+		s.Update(tick(i))
+		ev := tick(i)
+		ev.Source, ev.Price = "coinbase", ev.Price+1000
+		s.Update(ev)
+	}
+	if got := s.Range("binance", "BTCUSDT", t0, t0.Add(time.Hour)); len(got) != 4 || got[3].Price != 3 {
+		t.Fatalf("binance ticks = %+v", got)
+	}
+	if got := s.Last("coinbase", "BTCUSDT", 10); len(got) != 4 || got[0].Price != 1000 {
+		t.Fatalf("coinbase ticks = %+v", got)
+	}
+
+	// A late tick goes to its place; one older than a full ring is dropped.
+	late := tick(1)
+	late.Timestamp, late.Price = late.Timestamp.Add(500*time.Millisecond), 1.5
+	s.Update(late)
+	s.Update(tick(-5))
+	got := s.Last("binance", "BTCUSDT", 10)
+	want := []float64{0, 1, 1.5, 2, 3}
+	if len(got) != len(want) {
+		t.Fatalf("ticks = %+v", got)
+	}
+	for i := range want {
+		if got[i].Price != want[i] {
+			t.Fatalf("tick %d = %v, want %v", i, got[i].Price, want[i])
+		}
+	}
+	s.Update(tick(4)) // evicts tick 0
+	late.Timestamp, late.Price = t0.Add(1200*time.Millisecond), 1.2
+	s.Update(late) // evicts tick 1
+	if got := s.Range("binance", "BTCUSDT", t0, t0.Add(2*time.Second)); len(got) != 3 || got[0].Price != 1.2 || got[2].Price != 2 {
+		t.Fatalf("range after late tick in a full ring = %+v", got)
+	}
+
+	// Late candles are inserted in order and revisions stay on their venue.
+	for _, i := range []int{0, 2, 1} {
+		start := t0.Add(time.Duration(i) * time.Minute)
+		s.AddCandle(candle.Candle{Symbol: "BTCUSDT", Source: "binance", Start: start, Close: float64(i)})
+		s.AddCandle(candle.Candle{Symbol: "BTCUSDT", Source: "kraken", Start: start, Close: float64(i)})
+	}
+	s.ReviseCandle(candle.Candle{Symbol: "BTCUSDT", Source: "kraken", Start: t0.Add(time.Minute), Close: 42, Revision: 1})
+	cs := s.CandleRange("binance", "BTCUSDT", t0, t0.Add(time.Hour))
+	if len(cs) != 3 || cs[0].Close != 0 || cs[1].Close != 1 || cs[2].Close != 2 {
+		t.Fatalf("binance candles = %+v", cs)
+	}
+	if cs := s.LastCandles("kraken", "BTCUSDT", 10); len(cs) != 3 || cs[1].Close != 42 {
+		t.Fatalf("kraken candles = %+v", cs)
+	}
+}
+
+func TestHistoryConcurrentAccess(t *testing.T) {
+	s := NewPriceStore(WithRetention(Retention{Ticks: 500}))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				ev := tick(i)
+				ev.Symbol = fmt.Sprintf("SYM%d", w)
+				s.Update(ev)
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				_ = s.Last("binance", "SYM0", 10)
+				_ = s.Range("binance", "SYM1", t0, t0.Add(time.Minute))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkHistoryUpdate(b *testing.B) {
+	s := NewPriceStore(WithRetention(Retention{Ticks: 100000, MaxAge: time.Hour}))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.Update(tick(i))
+	}
+}
+
+func BenchmarkHistoryRange(b *testing.B) {
+	s := NewPriceStore(WithRetention(Retention{Ticks: 100000}))
+	for i := 0; i < 100000; i++ {
+		s.Update(tick(i))
+	}
+	from, to := t0.Add(50000*time.Second), t0.Add(50100*time.Second)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s.Range("binance", "BTCUSDT", from, to)
+	}
+}
+
+func BenchmarkHistoryLast(b *testing.B) {
+	s := NewPriceStore(WithRetention(Retention{Ticks: 100000}))
+	for i := 0; i < 100000; i++ {
+		s.Update(tick(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s.Last("binance", "BTCUSDT", 100)
+	}
+}
+
+func BenchmarkHistoryParallelReadWrite(b *testing.B) {
+	s := NewPriceStore(WithRetention(Retention{Ticks: 100000}))
+	for i := 0; i < 10000; i++ {
+		s.Update(tick(i))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if i%10 == 0 {
+				s.Update(tick(10000 + i))
+			} else {
+				_ = s.Last("binance", "BTCUSDT", 50)
+			}
+			i++
+		}
+	})
+}
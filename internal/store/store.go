@@ -1,33 +1,176 @@
 package store
 
 import (
-	"realtime-market-engine/internal/types"
 	"sync"
+	"time"
+
+	"realtime-market-engine/internal/candle"
+	"realtime-market-engine/internal/types"
 )
 
+// Retention bounds the history kept by PriceStore per venue and symbol. Zero counts
+// disable that history; a zero MaxAge keeps entries until they are evicted by count.
+type Retention struct {
+	Ticks   int
+	Candles int
+	MaxAge  time.Duration
+}
+
+type Option func(*PriceStore)
+
+// WithRetention enables tick and candle history.
+func WithRetention(r Retention) Option {
+	return func(s *PriceStore) { s.retention = r }
+}
+
 type PriceStore struct {
-	mu     sync.RWMutex
-	prices map[string]types.PriceEvent
+	mu        sync.RWMutex
+	prices    map[string]types.PriceEvent
+	quotes    map[string]types.Quote
+	ticks     map[string]*ring[types.PriceEvent]
+	candles   map[string]*ring[candle.Candle]
+	retention Retention
 }
 
-func NewPriceStore() *PriceStore {
-	return &PriceStore{
-		prices: make(map[string]types.PriceEvent),
+func NewPriceStore(opts ...Option) *PriceStore {
+	s := &PriceStore{
+		prices:  make(map[string]types.PriceEvent),
+		quotes:  make(map[string]types.Quote),
+		ticks:   make(map[string]*ring[types.PriceEvent]),
+		candles: make(map[string]*ring[candle.Candle]),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // To write the latest price. Like BTCUSDT = 88000 -> add it in our map
 func (s *PriceStore) Update(event types.PriceEvent) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.prices[event.Symbol] = event
+	k := key(event.Source, event.Symbol)
+	s.prices[k] = event
 
+	if s.retention.Ticks > 0 {
+		r, ok := s.ticks[k]
+		if !ok {
+			r = newRing(s.retention.Ticks, func(ev types.PriceEvent) time.Time { return ev.Timestamp })
+			s.ticks[k] = r
+		}
+		r.push(event, s.retention.MaxAge)
+	}
 }
 
-// To Get the latest current price
-func (s *PriceStore) Get(symbol string) (types.PriceEvent, bool) {
+// To Get the latest current price of symbol on source
+func (s *PriceStore) Get(source, symbol string) (types.PriceEvent, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	ev, ok := s.prices[symbol]
+	ev, ok := s.prices[key(source, symbol)]
 	return ev, ok
 }
+
+// To write the latest best bid/ask. Older updates (lower update ID) are ignored
+func (s *PriceStore) UpdateQuote(q types.Quote) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := key(q.Source, q.Symbol)
+	if cur, ok := s.quotes[k]; ok && q.UpdateID != 0 && q.UpdateID < cur.UpdateID {
+		return
+	}
+	s.quotes[k] = q
+}
+
+// To Get the latest best bid/ask of symbol on source
+func (s *PriceStore) GetQuote(source, symbol string) (types.Quote, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q, ok := s.quotes[key(source, symbol)]
+	return q, ok
+}
+
+// To keep a completed candle in the history
+func (s *PriceStore) AddCandle(c candle.Candle) {
+	if s.retention.Candles <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := key(c.Source, c.Symbol)
+	r, ok := s.candles[k]
+	if !ok {
+		r = newRing(s.retention.Candles, func(c candle.Candle) time.Time { return c.Start })
+		s.candles[k] = r
+	}
+	r.push(c, s.retention.MaxAge)
+}
+
+// ReviseCandle replaces the kept candle with the same source, symbol and start
+// as c, e.g. after late ticks amended it. Candles not in the history are ignored.
+func (s *PriceStore) ReviseCandle(c candle.Candle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.candles[key(c.Source, c.Symbol)]; ok {
+		r.replace(c)
+	}
+}
+
+// Range returns the ticks of symbol on source with from <= Timestamp <= to, oldest first.
+func (s *PriceStore) Range(source, symbol string, from, to time.Time) []types.PriceEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.ticks[key(source, symbol)]
+	if !ok {
+		return nil
+	}
+	return r.between(from, to)
+}
+
+// Last returns up to n most recent ticks of symbol on source, oldest first.
+func (s *PriceStore) Last(source, symbol string, n int) []types.PriceEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.ticks[key(source, symbol)]
+	if !ok {
+		return nil
+	}
+	return r.last(n)
+}
+
+// CandleRange returns the candles of symbol on source with from <= Start <= to, oldest first.
+func (s *PriceStore) CandleRange(source, symbol string, from, to time.Time) []candle.Candle {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.candles[key(source, symbol)]
+	if !ok {
+		return nil
+	}
+	return r.between(from, to)
+}
+
+// LastCandles returns up to n most recent candles of symbol on source, oldest first.
+func (s *PriceStore) LastCandles(source, symbol string, n int) []candle.Candle {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.candles[key(source, symbol)]
+	if !ok {
+		return nil
+	}
+	return r.last(n)
+}
+
+// key identifies the history of one venue and symbol; venues quoting the same
+// symbol must not share a ring.
+func key(source, symbol string) string {
+	return source + "|" + symbol
+}
+
+// Reset drops all prices, quotes and history, e.g. when a replay seeks.
+func (s *PriceStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.prices)
+	clear(s.quotes)
+	clear(s.ticks)
+	clear(s.candles)
+}
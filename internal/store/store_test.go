@@ -37,7 +37,7 @@ func TestStoreConcurrentAccess(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			for _, symbol := range symbols {
-				_, _ = store.Get(symbol)
+				_, _ = store.Get("test", symbol)
 			}
 		}()
 	}
@@ -46,3 +46,40 @@ func TestStoreConcurrentAccess(t *testing.T) {
 
 	// If this test passes without panic and -reace doesn't show error -> all good.
 }
+
+func TestStoreQuote(t *testing.T) {
+	store := NewPriceStore()
+
+	store.UpdateQuote(types.Quote{Symbol: "BTCUSDT", Source: "binance", Bid: 99.99, Ask: 100.01, UpdateID: 2})
+	store.UpdateQuote(types.Quote{Symbol: "BTCUSDT", Source: "binance", Bid: 50, Ask: 51, UpdateID: 1}) // stale
+
+	q, ok := store.GetQuote("binance", "BTCUSDT")
+	if !ok {
+		t.Fatal("quote not found")
+	}
+	if q.Bid != 99.99 || q.Ask != 100.01 {
+		t.Errorf("stale quote applied: %+v", q)
+	}
+	if q.Mid() != 100 {
+		t.Errorf("mid = %v, want 100", q.Mid())
+	}
+	if bps := q.SpreadBps(); bps < 1.999 || bps > 2.001 {
+		t.Errorf("spread = %v bps, want 2", bps)
+	}
+}
+
+func TestStorePricesPerVenue(t *testing.T) {
+	store := NewPriceStore()
+	now := time.Now()
+	store.Update(types.PriceEvent{Symbol: "BTCUSDT", Source: "binance", Price: 100, Timestamp: now})
+	store.Update(types.PriceEvent{Symbol: "BTCUSDT", Source: "kraken", Price: 101, Timestamp: now.Add(time.Millisecond)})
+
+	for source, want := range map[string]float64{"binance": 100, "kraken": 101} {
+		if ev, ok := store.Get(source, "BTCUSDT"); !ok || ev.Price != want {
+			t.Errorf("%s price = %+v, want %v", source, ev, want)
+		}
+	}
+	if _, ok := store.Get("coinbase", "BTCUSDT"); ok {
+		t.Error("got a price for a venue without trades")
+	}
+}
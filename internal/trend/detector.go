@@ -5,6 +5,7 @@ import (
 	"math"
 	"time"
 
+	"realtime-market-engine/internal/indicator"
 	"realtime-market-engine/internal/types"
 )
 
@@ -15,9 +16,26 @@ const (
 	DirectionDown Direction = "down"
 )
 
+// EMAMode selects how the EMAs are defined: by tick counts or by half-lives.
+type EMAMode string
+
+const (
+	EMAModeTick EMAMode = "tick"
+	EMAModeTime EMAMode = "time"
+)
+
+func ParseEMAMode(s string) (EMAMode, error) {
+	switch m := EMAMode(s); m {
+	case EMAModeTick, EMAModeTime:
+		return m, nil
+	}
+	return "", fmt.Errorf("trend: invalid EMA mode %q, want tick or time", s)
+}
+
 type TrendChange struct {
 	Type      string    `json:"type"`
 	Symbol    string    `json:"symbol"`
+	Source    string    `json:"source,omitempty"`
 	Trend     Direction `json:"trend"`
 	FastEMA   float64   `json:"fastEma"`
 	SlowEMA   float64   `json:"slowEma"`
@@ -29,12 +47,13 @@ type EMACrossoverDetector struct {
 	fastN int
 	slowN int
 
-	alphaFast float64
-	alphaSlow float64
+	// Half-lives of the EMAs in time mode; zero in tick mode.
+	fastHalfLife time.Duration
+	slowHalfLife time.Duration
 
-	fastEMA float64
-	slowEMA float64
-	hasEMA  bool
+	fast   *indicator.EMA
+	slow   *indicator.EMA
+	hasEMA bool
 
 	trend         Direction
 	hasTrend      bool
@@ -56,14 +75,14 @@ func (d *EMACrossoverDetector) EMAs() (fast float64, slow float64, ok bool) {
 	if !d.hasEMA {
 		return 0, 0, false
 	}
-	return d.fastEMA, d.slowEMA, true
+	return d.fast.Value(), d.slow.Value(), true
 }
 
 func (d *EMACrossoverDetector) CurrentDirection() (Direction, bool) {
 	if !d.hasEMA {
 		return "", false
 	}
-	if d.fastEMA >= d.slowEMA {
+	if d.fast.Value() >= d.slow.Value() {
 		return DirectionUp, true
 	}
 	return DirectionDown, true
@@ -92,17 +111,50 @@ func NewEMACrossoverDetector(fastN, slowN, confirmTicks int, minRelDiff float64,
 	return &EMACrossoverDetector{
 		fastN:        fastN,
 		slowN:        slowN,
-		alphaFast:    2.0 / (float64(fastN) + 1.0),
-		alphaSlow:    2.0 / (float64(slowN) + 1.0),
+		fast:         indicator.NewEMA(fastN),
+		slow:         indicator.NewEMA(slowN),
 		confirmTicks: confirmTicks,
 		minRelDiff:   minRelDiff,
 		cooldown:     cooldown,
 	}
 }
 
+// NewTimeEMACrossoverDetector is like NewEMACrossoverDetector with EMAs
+// defined by half-lives instead of tick counts. Each tick's alpha is derived
+// from the time since the previous tick, so the EMAs cover the same time in
+// busy and quiet markets; ticks no newer than the newest one so far (same
+// timestamp or late) do not move them and do not count towards confirmTicks.
+func NewTimeEMACrossoverDetector(fastHalfLife, slowHalfLife time.Duration, confirmTicks int, minRelDiff float64, cooldown time.Duration) *EMACrossoverDetector {
+	if fastHalfLife <= 0 {
+		fastHalfLife = 30 * time.Second
+	}
+	if slowHalfLife <= 0 {
+		slowHalfLife = 2 * time.Minute
+	}
+	if fastHalfLife >= slowHalfLife {
+		slowHalfLife = 2 * fastHalfLife
+	}
+	d := NewEMACrossoverDetector(1, 2, confirmTicks, minRelDiff, cooldown)
+	d.fastN, d.slowN = 0, 0
+	d.fastHalfLife = fastHalfLife
+	d.slowHalfLife = slowHalfLife
+	return d
+}
+
+// timeAlpha is the EMA weight of a value after dt for the given half-life.
+func timeAlpha(dt, halfLife time.Duration) float64 {
+	if dt <= 0 {
+		return 0
+	}
+	return 1 - math.Exp2(-float64(dt)/float64(halfLife))
+}
+
 func (d *EMACrossoverDetector) Push(ev types.PriceEvent) (TrendChange, bool) {
+	prev := d.lastTimestamp
 	d.lastSymbol = ev.Symbol
-	d.lastTimestamp = ev.Timestamp
+	if ev.Timestamp.After(prev) {
+		d.lastTimestamp = ev.Timestamp
+	}
 
 	price := ev.Price
 	if math.IsNaN(price) || math.IsInf(price, 0) {
@@ -110,14 +162,26 @@ func (d *EMACrossoverDetector) Push(ev types.PriceEvent) (TrendChange, bool) {
 	}
 
 	if !d.hasEMA {
-		d.fastEMA = price
-		d.slowEMA = price
+		d.fast.Seed(price)
+		d.slow.Seed(price)
 		d.hasEMA = true
 		return TrendChange{}, false
 	}
 
-	d.fastEMA = d.alphaFast*price + (1.0-d.alphaFast)*d.fastEMA
-	d.slowEMA = d.alphaSlow*price + (1.0-d.alphaSlow)*d.slowEMA
+	if d.fastHalfLife > 0 {
+		dt := ev.Timestamp.Sub(prev)
+		if dt <= 0 {
+			// The EMAs stay put, so the tick has nothing to confirm;
+			// a burst sharing a timestamp counts once.
+			return TrendChange{}, false
+		}
+		d.fast.AddWeighted(price, timeAlpha(dt, d.fastHalfLife))
+		d.slow.AddWeighted(price, timeAlpha(dt, d.slowHalfLife))
+	} else {
+		d.fast.Add(price)
+		d.slow.Add(price)
+	}
+	fastEMA, slowEMA := d.fast.Value(), d.slow.Value()
 
 	if d.cooldown > 0 && !d.lastChangeAt.IsZero() {
 		if ev.Timestamp.Sub(d.lastChangeAt) < d.cooldown {
@@ -126,7 +190,7 @@ func (d *EMACrossoverDetector) Push(ev types.PriceEvent) (TrendChange, bool) {
 	}
 
 	var current Direction
-	if d.fastEMA >= d.slowEMA {
+	if fastEMA >= slowEMA {
 		current = DirectionUp
 	} else {
 		current = DirectionDown
@@ -134,7 +198,7 @@ func (d *EMACrossoverDetector) Push(ev types.PriceEvent) (TrendChange, bool) {
 
 	relSep := 0.0
 	if price != 0 {
-		relSep = math.Abs(d.fastEMA-d.slowEMA) / math.Abs(price)
+		relSep = math.Abs(fastEMA-slowEMA) / math.Abs(price)
 	}
 	if relSep < d.minRelDiff {
 		return TrendChange{}, false
@@ -170,9 +234,10 @@ func (d *EMACrossoverDetector) Push(ev types.PriceEvent) (TrendChange, bool) {
 	return TrendChange{
 		Type:      "trend_change",
 		Symbol:    ev.Symbol,
+		Source:    ev.Source,
 		Trend:     current,
-		FastEMA:   d.fastEMA,
-		SlowEMA:   d.slowEMA,
+		FastEMA:   fastEMA,
+		SlowEMA:   slowEMA,
 		Price:     price,
 		Timestamp: ev.Timestamp,
 	}, true
@@ -183,7 +248,70 @@ func (d *EMACrossoverDetector) String() string {
 		return "EMA(n/a)"
 	}
 	if !d.hasTrend {
-		return fmt.Sprintf("EMA fast=%.6f slow=%.6f", d.fastEMA, d.slowEMA)
+		return fmt.Sprintf("EMA fast=%.6f slow=%.6f", d.fast.Value(), d.slow.Value())
+	}
+	return fmt.Sprintf("EMA fast=%.6f slow=%.6f trend=%s", d.fast.Value(), d.slow.Value(), d.trend)
+}
+
+// EMACrossoverSnapshot is the serializable state of an EMACrossoverDetector.
+type EMACrossoverSnapshot struct {
+	FastN         int           `json:"fastN"`
+	SlowN         int           `json:"slowN"`
+	FastHalfLife  time.Duration `json:"fastHalfLife,omitempty"`
+	SlowHalfLife  time.Duration `json:"slowHalfLife,omitempty"`
+	FastEMA       float64       `json:"fastEma"`
+	SlowEMA       float64       `json:"slowEma"`
+	HasEMA        bool          `json:"hasEma"`
+	Trend         Direction     `json:"trend,omitempty"`
+	HasTrend      bool          `json:"hasTrend"`
+	PendingTrend  Direction     `json:"pendingTrend,omitempty"`
+	PendingCount  int           `json:"pendingCount"`
+	LastChangeAt  time.Time     `json:"lastChangeAt"`
+	LastSymbol    string        `json:"lastSymbol,omitempty"`
+	LastTimestamp time.Time     `json:"lastTimestamp"`
+}
+
+func (d *EMACrossoverDetector) Snapshot() EMACrossoverSnapshot {
+	return EMACrossoverSnapshot{
+		FastN:         d.fastN,
+		SlowN:         d.slowN,
+		FastHalfLife:  d.fastHalfLife,
+		SlowHalfLife:  d.slowHalfLife,
+		FastEMA:       d.fast.Value(),
+		SlowEMA:       d.slow.Value(),
+		HasEMA:        d.hasEMA,
+		Trend:         d.trend,
+		HasTrend:      d.hasTrend,
+		PendingTrend:  d.pendingTrend,
+		PendingCount:  d.pendingCount,
+		LastChangeAt:  d.lastChangeAt,
+		LastSymbol:    d.lastSymbol,
+		LastTimestamp: d.lastTimestamp,
+	}
+}
+
+// Restore loads the EMAs and trend state from s. The EMA windows or half-lives must match,
+// otherwise the EMAs would not mean the same thing; confirmation, separation
+// and cooldown settings are taken from the detector.
+func (d *EMACrossoverDetector) Restore(s EMACrossoverSnapshot) error {
+	if s.FastN != d.fastN || s.SlowN != d.slowN {
+		return fmt.Errorf("trend: snapshot EMA windows %d/%d do not match %d/%d", s.FastN, s.SlowN, d.fastN, d.slowN)
+	}
+	if s.FastHalfLife != d.fastHalfLife || s.SlowHalfLife != d.slowHalfLife {
+		return fmt.Errorf("trend: snapshot EMA half-lives %s/%s do not match %s/%s", s.FastHalfLife, s.SlowHalfLife, d.fastHalfLife, d.slowHalfLife)
+	}
+	d.fast, d.slow = indicator.NewEMA(d.fastN), indicator.NewEMA(d.slowN)
+	if s.HasEMA {
+		d.fast.Seed(s.FastEMA)
+		d.slow.Seed(s.SlowEMA)
 	}
-	return fmt.Sprintf("EMA fast=%.6f slow=%.6f trend=%s", d.fastEMA, d.slowEMA, d.trend)
+	d.hasEMA = s.HasEMA
+	d.trend = s.Trend
+	d.hasTrend = s.HasTrend
+	d.pendingTrend = s.PendingTrend
+	d.pendingCount = s.PendingCount
+	d.lastChangeAt = s.LastChangeAt
+	d.lastSymbol = s.LastSymbol
+	d.lastTimestamp = s.LastTimestamp
+	return nil
 }
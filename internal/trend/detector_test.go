@@ -0,0 +1,117 @@
+package trend
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"realtime-market-engine/internal/types"
+)
+
+// feed pushes a price stepping from 100 to 110 at 10s, sampled every step,
+// and returns the EMAs at 20s.
+func feed(d *EMACrossoverDetector, step time.Duration) (float64, float64) {
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	for t := time.Duration(0); t <= 20*time.Second; t += step {
+		price := 100.0
+		if t >= 10*time.Second {
+			price = 110
+		}
+		d.Push(types.PriceEvent{Symbol: "BTCUSDT", Price: price, Timestamp: t0.Add(t)})
+	}
+	fast, slow, _ := d.EMAs()
+	return fast, slow
+}
+
+func TestTimeEMAIndependentOfTickRate(t *testing.T) {
+	newTime := func() *EMACrossoverDetector {
+		return NewTimeEMACrossoverDetector(5*time.Second, 20*time.Second, 1, 0, 0)
+	}
+	busyFast, busySlow := feed(newTime(), 10*time.Millisecond)
+	quietFast, quietSlow := feed(newTime(), time.Second)
+
+	// After 10s at 110 (two fast half-lives) the fast EMA is 3/4 of the way
+	// there, whatever the tick rate. The first tick at 110 also weighs the gap
+	// before it, so sparse ticks land up to one step ahead.
+	if math.Abs(busyFast-107.5) > 0.05 || math.Abs(quietFast-busyFast) > 0.4 {
+		t.Errorf("fast EMA busy=%.4f quiet=%.4f, want about 107.5", busyFast, quietFast)
+	}
+	if math.Abs(quietSlow-busySlow) > 0.4 {
+		t.Errorf("slow EMA busy=%.4f quiet=%.4f", busySlow, quietSlow)
+	}
+
+	// Tick-count EMAs depend on the tick rate.
+	tickFast, _ := feed(NewEMACrossoverDetector(20, 50, 1, 0, 0), time.Second)
+	busyTickFast, _ := feed(NewEMACrossoverDetector(20, 50, 1, 0, 0), 10*time.Millisecond)
+	if math.Abs(tickFast-busyTickFast) < 1 {
+		t.Errorf("tick EMAs unexpectedly close: %.4f vs %.4f", tickFast, busyTickFast)
+	}
+}
+
+func TestTimeEMASnapshotMismatch(t *testing.T) {
+	d := NewTimeEMACrossoverDetector(5*time.Second, 20*time.Second, 1, 0, 0)
+	feed(d, time.Second)
+	s := d.Snapshot()
+
+	if err := NewTimeEMACrossoverDetector(5*time.Second, 20*time.Second, 1, 0, 0).Restore(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewTimeEMACrossoverDetector(5*time.Second, time.Minute, 1, 0, 0).Restore(s); err == nil {
+		t.Error("Restore accepted different half-lives")
+	}
+	if err := NewEMACrossoverDetector(20, 50, 1, 0, 0).Restore(s); err == nil {
+		t.Error("tick mode detector accepted a time mode snapshot")
+	}
+}
+
+func TestTimeEMALateTick(t *testing.T) {
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	d := NewTimeEMACrossoverDetector(5*time.Second, 20*time.Second, 1, 0, 0)
+	want := NewTimeEMACrossoverDetector(5*time.Second, 20*time.Second, 1, 0, 0)
+	for _, s := range []int{0, 1, 2, 3} {
+		ev := types.PriceEvent{Symbol: "BTCUSDT", Price: 100 + float64(s), Timestamp: t0.Add(time.Duration(s) * time.Second)}
+		d.Push(ev)
+		want.Push(ev)
+	}
+
+	// A late tick neither moves the EMAs nor the clock the next tick's
+	// weight is measured from.
+	d.Push(types.PriceEvent{Symbol: "BTCUSDT", Price: 50, Timestamp: t0.Add(time.Second)})
+	if got := d.Snapshot().LastTimestamp; !got.Equal(t0.Add(3 * time.Second)) {
+		t.Fatalf("last timestamp = %s after a late tick", got)
+	}
+	next := types.PriceEvent{Symbol: "BTCUSDT", Price: 110, Timestamp: t0.Add(4 * time.Second)}
+	d.Push(next)
+	want.Push(next)
+
+	fast, slow, _ := d.EMAs()
+	wantFast, wantSlow, _ := want.EMAs()
+	if fast != wantFast || slow != wantSlow {
+		t.Errorf("EMAs = %v/%v, want %v/%v", fast, slow, wantFast, wantSlow)
+	}
+}
+
+func TestTimeEMABurstConfirmsOnce(t *testing.T) {
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	d := NewTimeEMACrossoverDetector(5*time.Second, 20*time.Second, 3, 0, 0)
+	push := func(s int, price float64) (TrendChange, bool) {
+		return d.Push(types.PriceEvent{Symbol: "BTCUSDT", Price: price, Timestamp: t0.Add(time.Duration(s) * time.Second)})
+	}
+	for s := 0; s <= 10; s++ {
+		push(s, 100+float64(s))
+	}
+
+	// The crash turns the EMAs down; the rest of the burst at the same
+	// timestamp brings no new confirmation.
+	for i := 0; i < 5; i++ {
+		if c, ok := push(11, 50); ok {
+			t.Fatalf("trend change %+v within the burst", c)
+		}
+	}
+	if _, ok := push(12, 50); ok {
+		t.Fatal("trend change after two confirmations")
+	}
+	if c, ok := push(13, 50); !ok || c.Trend != DirectionDown {
+		t.Fatalf("third confirmation = %+v, %v; want a change to down", c, ok)
+	}
+}
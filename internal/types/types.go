@@ -6,6 +6,46 @@ import "time"
 type PriceEvent struct {
 	Symbol    string
 	Price     float64
+	Quantity  float64
 	Timestamp time.Time
 	Source    string // "Binance"
+
+	// Trade identity as reported by the venue; zero when unknown.
+	AggTradeID   int64
+	FirstTradeID int64
+	LastTradeID  int64
+	BuyerIsMaker bool
+}
+
+// TradeCount returns the number of individual trades the event aggregates.
+func (e PriceEvent) TradeCount() int64 {
+	if e.LastTradeID >= e.FirstTradeID && e.LastTradeID > 0 {
+		return e.LastTradeID - e.FirstTradeID + 1
+	}
+	return 1
+}
+
+// Quote is the best bid/ask of a symbol.
+type Quote struct {
+	Symbol    string    `json:"symbol"`
+	Bid       float64   `json:"bid"`
+	BidQty    float64   `json:"bidQty"`
+	Ask       float64   `json:"ask"`
+	AskQty    float64   `json:"askQty"`
+	UpdateID  int64     `json:"updateId"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+}
+
+func (q Quote) Mid() float64 {
+	return (q.Bid + q.Ask) / 2
+}
+
+// SpreadBps returns the quoted spread in basis points of the mid price.
+func (q Quote) SpreadBps() float64 {
+	mid := q.Mid()
+	if mid == 0 {
+		return 0
+	}
+	return (q.Ask - q.Bid) / mid * 10000
 }
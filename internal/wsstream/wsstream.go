@@ -0,0 +1,69 @@
+package wsstream
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Run dials url, calls subscribe (when non-nil) on every new connection, hands
+// every frame to onMessage and reconnects with exponential backoff until ctx
+// is done. onMessage returns false to stop reading.
+func Run(ctx context.Context, dialer *websocket.Dialer, url, name string, subscribe func(*websocket.Conn) error, onMessage func([]byte) bool) {
+	backoff := 200 * time.Millisecond
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		conn, _, err := dialer.DialContext(ctx, url, nil)
+		if err == nil && subscribe != nil {
+			if err = subscribe(conn); err != nil {
+				_ = conn.Close()
+			}
+		}
+		if err != nil {
+			log.Printf("%s dial error: %v", name, err)
+			select {
+			case <-time.After(backoff):
+				if backoff < 5*time.Second {
+					backoff *= 2
+				}
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		backoff = 200 * time.Millisecond
+		log.Printf("connected to %s", name)
+
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			defer conn.Close()
+
+			for {
+				_, message, err := conn.ReadMessage()
+				if err != nil {
+					log.Printf("%s read error: %v", name, err)
+					return
+				}
+				if !onMessage(message) {
+					return
+				}
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			<-readDone
+			return
+		case <-readDone:
+			continue
+		}
+	}
+}